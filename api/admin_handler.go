@@ -0,0 +1,114 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/fullstack/dev-overflow/db"
+	"github.com/fullstack/dev-overflow/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+type AdminHandler struct {
+	userStore db.UserStore
+	tagStore db.TagStore
+}
+
+func NewAdminHandler(userStore db.UserStore, tagStore db.TagStore) *AdminHandler {
+	return &AdminHandler{
+		userStore: userStore,
+		tagStore: tagStore,
+	}
+}
+
+func (h *AdminHandler) HandleGetUser(ctx *fiber.Ctx) error {
+	var (
+		clerkID = ctx.Params("clerkID")
+	)
+
+	user, err := h.userStore.GetUserByID(ctx.Context(), clerkID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(clerkID)
+		}
+		return err
+	}
+
+	return ctx.JSON(user.Private())
+}
+
+func (h *AdminHandler) HandleUpdateUserRole(ctx *fiber.Ctx) error {
+	var (
+		clerkID = ctx.Params("clerkID")
+		params types.UpdateUserRoleParams
+	)
+
+	if err := ctx.BodyParser(&params); err != nil {
+		return ErrBadRequest()
+	}
+
+	if errors := params.Validate(); len(errors) > 0 {
+		return ErrValidation(errors)
+	}
+
+	if err := h.userStore.UpdateUserRole(ctx.Context(), clerkID, params.Role); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(clerkID)
+		}
+		return err
+	}
+
+	return ctx.JSON(fiber.Map{"message": "User role updated", "clerkID": clerkID, "role": params.Role})
+}
+
+func (h *AdminHandler) HandleUpdateUser(ctx *fiber.Ctx) error {
+	var (
+		clerkID = ctx.Params("clerkID")
+		params types.AdminUpdateUserParam
+	)
+
+	if err := parseStrictBody(ctx, &params); err != nil {
+		return err
+	}
+
+	if errors := params.Validate(); len(errors) > 0 {
+		return ErrValidation(errors)
+	}
+
+	user, err := h.userStore.UpdateUser(ctx.Context(), clerkID, params)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(clerkID)
+		}
+		return err
+	}
+
+	return ctx.JSON(user.Private())
+}
+
+func (h *AdminHandler) HandleEditTag(ctx *fiber.Ctx) error {
+	var (
+		id = ctx.Params("_id")
+		params types.EditTagParams
+	)
+
+	if err := ctx.BodyParser(&params); err != nil {
+		return ErrBadRequest()
+	}
+
+	if errors := params.Validate(); len(errors) > 0 {
+		return ErrValidation(errors)
+	}
+
+	tag, err := h.tagStore.EditTag(ctx.Context(), id, &params)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(id)
+		}
+		if errors.Is(err, db.ErrTagExists) {
+			return ErrConflict(err.Error())
+		}
+		return err
+	}
+
+	return ctx.JSON(tag)
+}
@@ -2,26 +2,38 @@ package api
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/fullstack/dev-overflow/db"
 	"github.com/fullstack/dev-overflow/types"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type AnswerHandler struct {
 	answerStore db.AnswerStore
 	questionStore db.QuestionStore
 	userStore db.UserStore
+	interactionStore db.InteractionStore
+	reputationStore db.ReputationStore
+	commentStore db.CommentStore
+	revisionStore db.RevisionStore
+	voteStore db.VoteStore
+	quotaStore db.QuotaStore
 }
 
-func NewAnswerHandler(answerStore db.AnswerStore, questionStore db.QuestionStore, userStore db.UserStore) *AnswerHandler {
+func NewAnswerHandler(answerStore db.AnswerStore, questionStore db.QuestionStore, userStore db.UserStore, interactionStore db.InteractionStore, reputationStore db.ReputationStore, commentStore db.CommentStore, revisionStore db.RevisionStore, voteStore db.VoteStore, quotaStore db.QuotaStore) *AnswerHandler {
 	return &AnswerHandler{
 		answerStore: answerStore,
 		questionStore: questionStore,
 		userStore: userStore,
+		interactionStore: interactionStore,
+		reputationStore: reputationStore,
+		commentStore: commentStore,
+		revisionStore: revisionStore,
+		voteStore: voteStore,
+		quotaStore: quotaStore,
 	}
 }
 
@@ -33,100 +45,156 @@ func (h *AnswerHandler) HandleGetAnswerByID(ctx *fiber.Ctx) error {
 
 	question, err := h.questionStore.GetQuestionByID(ctx.Context(), questionID)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
+		if errors.Is(err, db.ErrNotFound) {
 			return ErrResourceNotFound(questionID)
 		}
+		return err
 	}
 
 	answer, err := h.answerStore.GetAnswerByID(ctx.Context(), answerID)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
+		if errors.Is(err, db.ErrNotFound) {
 			return ErrResourceNotFound(answerID)
 		}
+		return err
 	}
 
 	if answer.QuestionID != question.ID {
-		return ErrBadRequest()
+		return ErrResourceNotFound(answerID)
+	}
+
+	renderAnswers(ctx.Context(), h.answerStore, answer)
+	if err := setAnswerVotes(ctx.Context(), h.voteStore, getOptionalUser(ctx), answer); err != nil {
+		return err
 	}
 
 	return ctx.JSON(answer)
 }
 
+// HandleAnswerVote sets the caller's vote on the answer to up, down or none
+// and returns it with the new score.
 func (h *AnswerHandler) HandleAnswerVote(ctx *fiber.Ctx) error {
-	var params types.VoteAnswerParams
+	var (
+		id = ctx.Params("id")
+		params types.VoteParams
+	)
 
 	if err := ctx.BodyParser(&params); err != nil {
 		return ErrBadRequest()
 	}
 
-	answer, err := h.answerStore.GetAnswerByID(ctx.Context(), params.AnswerID)
+	if errors := params.Validate(); len(errors) > 0 {
+		return ErrValidation(errors)
+	}
+
+	user, err := getAuthUser(ctx)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return ErrResourceNotFound(params.AnswerID)
-		}
+		return err
 	}
 
-	user, err := h.userStore.GetUserByID(ctx.Context(), params.UserID)
+	answer, err := h.answerStore.GetAnswerByID(ctx.Context(), id)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return ErrResourceNotFound(params.UserID)
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(id)
 		}
+		return err
 	}
 
-	if answer.UserID == user.ID {
-		return ErrUnauthorized()
+	if !types.Authorize(user, types.ActionVotePost, answer.UserID) {
+		return ErrForbidden()
 	}
 
-	if !params.HasUpvoted && !params.HasDownvoted {
-		return ErrBadRequest()
+	next := *params.Vote
+	if priv, ok := types.VotePrivilege(next); ok {
+		if err := requirePrivilege(user, priv); err != nil {
+			return err
+		}
 	}
 
-	if params.HasUpvoted {
-		if err := h.answerStore.UpvoteAnswer(ctx.Context(), &params); err != nil {
-			return ErrBadRequest()
-		}
+	prev, err := h.voteStore.GetVote(ctx.Context(), user.ID, answer.ID)
+	if err != nil {
+		return err
 	}
 
-	if params.HasDownvoted {
-		if err := h.answerStore.DownvoteAnswer(ctx.Context(), &params); err != nil {
-			return ErrBadRequest()
+	counted := next != types.VoteNone && next != prev
+	if counted {
+		if err := consumeQuota(ctx.Context(), h.quotaStore, user, types.QuotaVotes); err != nil {
+			return err
 		}
 	}
 
-	answer, err = h.answerStore.GetAnswerByID(ctx.Context(), params.AnswerID)
+	vote := &types.Vote{
+		UserID: user.ID,
+		TargetID: answer.ID,
+		TargetType: types.VoteOnAnswer,
+		QuestionID: answer.QuestionID,
+		OwnerID: answer.UserID,
+		Vote: next,
+	}
+
+	result, err := h.voteStore.CastVote(ctx.Context(), vote, prev)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return ErrResourceNotFound(params.AnswerID)
+		if counted {
+			if err := refundQuota(ctx.Context(), h.quotaStore, user, types.QuotaVotes); err != nil {
+				return err
+			}
 		}
+		return err
 	}
-	return ctx.JSON(answer)
+
+	return ctx.JSON(result)
 }
 
 func (h *AnswerHandler) HandleGetAnswersByUserID(ctx *fiber.Ctx) error {
 	var (
 		id = ctx.Params("id")
+		params db.AnswerQueryParams
 	)
 
-	answers, err := h.answerStore.GetAnswersByUserID(ctx.Context(), id)
-	if err != nil {
+	if err := ctx.QueryParser(&params); err != nil {
 		return ErrBadRequest()
 	}
 
+	answers, err := h.answerStore.GetAnswersByUserID(ctx.Context(), id, &params)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(id)
+		}
+		return err
+	}
+
+	renderAnswers(ctx.Context(), h.answerStore, answers.Answers...)
+	if err := setAnswerVotes(ctx.Context(), h.voteStore, getOptionalUser(ctx), answers.Answers...); err != nil {
+		return err
+	}
+
 	return ctx.JSON(answers)
 }
 
-
 func (h *AnswerHandler) HandleGetAnswersOfQuestion(ctx *fiber.Ctx) error {
-
 	var (
 		id = ctx.Params("id")
+		params db.AnswerQueryParams
 	)
 
-	answers, err := h.answerStore.GetAnswersOfQuestion(ctx.Context(), id)
-	if err != nil {
+	if err := ctx.QueryParser(&params); err != nil {
 		return ErrBadRequest()
 	}
 
+	answers, err := h.answerStore.GetAnswersOfQuestion(ctx.Context(), id, &params)
+	if err != nil {
+		return err
+	}
+
+	renderAnswers(ctx.Context(), h.answerStore, answers.Answers...)
+	if err := setAnswerVotes(ctx.Context(), h.voteStore, getOptionalUser(ctx), answers.Answers...); err != nil {
+		return err
+	}
+
+	if err := embedAnswerComments(ctx.Context(), h.commentStore, answers.Answers); err != nil {
+		return err
+	}
+
 	return ctx.JSON(answers)
 }
 
@@ -136,6 +204,32 @@ func (h *AnswerHandler) HandleCreateAnswer(ctx *fiber.Ctx) error {
 	)
 
 	if err := ctx.BodyParser(&params); err != nil {
+		return ErrBadRequest()
+	}
+
+	if errors := params.Validate(); len(errors) > 0 {
+		return ErrValidation(errors)
+	}
+
+	user, err := getAuthUser(ctx)
+	if err != nil {
+		return err
+	}
+	params.UserID = user.ID
+
+	question, err := h.questionStore.GetQuestionByID(ctx.Context(), params.QuestionID.Hex())
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(params.QuestionID.Hex())
+		}
+		return err
+	}
+
+	if question.IsClosed() {
+		return ErrConflict("Question is closed and not accepting answers").WithCode(CodeQuestionClosed)
+	}
+
+	if err := consumeQuota(ctx.Context(), h.quotaStore, user, types.QuotaAnswers); err != nil {
 		return err
 	}
 
@@ -143,22 +237,312 @@ func (h *AnswerHandler) HandleCreateAnswer(ctx *fiber.Ctx) error {
 		UserID: params.UserID,
 		QuestionID: params.QuestionID,
 		Description: params.Description,
-		Upvotes: []primitive.ObjectID{},
-		Downvotes: []primitive.ObjectID{},
 		CreatedAt: time.Now().UTC(),
 	}
 
-	answer, err := h.answerStore.CreateAnswer(ctx.Context(), answer)
+	answer, err = h.answerStore.CreateAnswer(ctx.Context(), answer)
+	if err != nil {
+		if err := refundQuota(ctx.Context(), h.quotaStore, user, types.QuotaAnswers); err != nil {
+			return err
+		}
+		return err
+	}
+
+	return ctx.JSON(answer)
+}
+
+func (h *AnswerHandler) HandleDeleteAnswer(ctx *fiber.Ctx) error {
+	var (
+		id = ctx.Params("id")
+	)
+
+	user, err := getAuthUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	answer, err := h.answerStore.GetAnswerByID(ctx.Context(), id)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(id)
+		}
+		return err
+	}
+
+	if !types.Authorize(user, types.ActionDeletePost, answer.UserID) {
+		return ErrForbidden()
+	}
+
+	if err := h.answerStore.DeleteAnswer(ctx.Context(), answer); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(id)
+		}
+		return err
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Answer deleted", "id": id})
+}
+
+func (h *AnswerHandler) HandleAcceptAnswer(ctx *fiber.Ctx) error {
+	return h.setAccepted(ctx, true)
+}
+
+func (h *AnswerHandler) HandleUnacceptAnswer(ctx *fiber.Ctx) error {
+	return h.setAccepted(ctx, false)
+}
+
+// setAccepted lets the question owner accept or un-accept an answer and
+// records it in the asker's and answerer's activity.
+func (h *AnswerHandler) setAccepted(ctx *fiber.Ctx, accept bool) error {
+	var (
+		id = ctx.Params("id")
+	)
+
+	user, err := getAuthUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	answer, err := h.answerStore.GetAnswerByID(ctx.Context(), id)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(id)
+		}
+		return err
+	}
+
+	question, err := h.questionStore.GetQuestionByID(ctx.Context(), answer.QuestionID.Hex())
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(answer.QuestionID.Hex())
+		}
+		return err
+	}
+
+	if question.UserID != user.ID {
+		return ErrForbidden()
+	}
+
+	if !accept && !answer.IsAccepted {
+		return ErrConflict("Answer is not accepted")
+	}
+
+	if err := h.answerStore.AcceptAnswer(ctx.Context(), question, answer, accept); err != nil {
+		if errors.Is(err, db.ErrAcceptChanged) {
+			return ErrConflict("Accepted answer changed, reload the question and try again")
+		}
+		return err
+	}
+
+	now := time.Now().UTC()
+	activity := []*types.Interaction{
+		{UserID: user.ID, Action: types.InteractionUnacceptAnswer, QuestionID: question.ID, AnswerID: answer.ID, Tags: question.Tags, CreatedAt: now},
+	}
+	if accept {
+		activity = []*types.Interaction{
+			{UserID: user.ID, Action: types.InteractionAcceptAnswer, QuestionID: question.ID, AnswerID: answer.ID, Tags: question.Tags, CreatedAt: now},
+			{UserID: answer.UserID, Action: types.InteractionAnswerAccepted, QuestionID: question.ID, AnswerID: answer.ID, Tags: question.Tags, CreatedAt: now},
+		}
+	}
+
+	for _, interaction := range activity {
+		if _, err := h.interactionStore.CreateInteraction(ctx.Context(), interaction); err != nil {
+			return err
+		}
+	}
+
+	answer, err = h.answerStore.GetAnswerByID(ctx.Context(), id)
 	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(answer)
+}
+
+func (h *AnswerHandler) HandleEditAnswer(ctx *fiber.Ctx) error {
+	var (
+		id = ctx.Params("id")
+		params types.EditAnswerParams
+	)
+
+	if err := ctx.BodyParser(&params); err != nil {
 		return ErrBadRequest()
 	}
 
-	if err := h.questionStore.UpdateQuestionAnswersField(ctx.Context(), &types.UpdateQuestionAnswersParams{
-		QuestionID: params.QuestionID.Hex(),
-		Answers: answer.ID,
-	}); err != nil {
+	if errors := params.Validate(); len(errors) > 0 {
+		return ErrValidation(errors)
+	}
+
+	return h.reviseAnswer(ctx, id, params.Description, params.EditSummary, 0)
+}
+
+// HandleRollbackAnswer restores an older revision by recording it again as
+// the newest one, so the history is never rewritten.
+func (h *AnswerHandler) HandleRollbackAnswer(ctx *fiber.Ctx) error {
+	var (
+		id = ctx.Params("id")
+		params types.RollbackAnswerParams
+	)
+
+	if err := ctx.BodyParser(&params); err != nil {
 		return ErrBadRequest()
 	}
 
+	if errors := params.Validate(); len(errors) > 0 {
+		return ErrValidation(errors)
+	}
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidID()
+	}
+
+	target, err := h.revisionStore.GetAnswerRevision(ctx.Context(), oid, params.Revision)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(fmt.Sprintf("revision %d", params.Revision))
+		}
+		return err
+	}
+
+	summary := params.EditSummary
+	if summary == "" {
+		summary = fmt.Sprintf("Rolled back to revision %d", target.Revision)
+	}
+
+	return h.reviseAnswer(ctx, id, target.Description, summary, target.Revision)
+}
+
+func (h *AnswerHandler) HandleGetAnswerRevisions(ctx *fiber.Ctx) error {
+	var (
+		id = ctx.Params("id")
+	)
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidID()
+	}
+
+	revisions, err := h.revisionStore.GetAnswerRevisions(ctx.Context(), oid)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(revisions)
+}
+
+func (h *AnswerHandler) HandleGetAnswerRevisionDiff(ctx *fiber.Ctx) error {
+	var (
+		id = ctx.Params("id")
+		from = ctx.QueryInt("from")
+		to = ctx.QueryInt("to")
+	)
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidID()
+	}
+
+	if from <= 0 || to <= 0 {
+		return NewError(fiber.StatusBadRequest, "from and to revisions are required")
+	}
+
+	fromRevision, err := h.revisionStore.GetAnswerRevision(ctx.Context(), oid, from)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(fmt.Sprintf("revision %d", from))
+		}
+		return err
+	}
+
+	toRevision, err := h.revisionStore.GetAnswerRevision(ctx.Context(), oid, to)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(fmt.Sprintf("revision %d", to))
+		}
+		return err
+	}
+
+	return ctx.JSON(types.NewAnswerRevisionDiff(fromRevision, toRevision))
+}
+
+// reviseAnswer records description as the answer's next revision and
+// applies it. rollbackOf is the revision being restored, if any.
+func (h *AnswerHandler) reviseAnswer(ctx *fiber.Ctx, id, description, summary string, rollbackOf int) error {
+	user, err := getAuthUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	answer, err := h.answerStore.GetAnswerByID(ctx.Context(), id)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(id)
+		}
+		return err
+	}
+
+	if !types.Authorize(user, types.ActionEditPost, answer.UserID) {
+		return ErrForbidden()
+	}
+
+	revisions, err := h.revisionStore.GetAnswerRevisions(ctx.Context(), answer.ID)
+	if err != nil {
+		return err
+	}
+
+	// Answers posted before revisions existed get their original version
+	// recorded on the first edit. A concurrent first edit may have recorded
+	// it already.
+	if len(revisions) == 0 {
+		_, err := h.revisionStore.CreateAnswerRevision(ctx.Context(), &types.AnswerRevision{
+			AnswerID: answer.ID,
+			QuestionID: answer.QuestionID,
+			Revision: 1,
+			Description: answer.Description,
+			EditorID: answer.UserID,
+			CreatedAt: answer.CreatedAt,
+		})
+		if err != nil && !errors.Is(err, db.ErrConflict) {
+			return err
+		}
+	}
+
+	// As with questions, the unique (answerID, revision) index and the
+	// conditional EditAnswer let only one of two concurrent edits through.
+	revision, err := h.revisionStore.CreateAnswerRevision(ctx.Context(), &types.AnswerRevision{
+		AnswerID: answer.ID,
+		QuestionID: answer.QuestionID,
+		Revision: types.NextRevision(answer.Revision),
+		Description: description,
+		EditorID: user.ID,
+		EditSummary: summary,
+		RollbackOf: rollbackOf,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrConflict) {
+			return ErrEditConflict()
+		}
+		return err
+	}
+
+	if err := h.answerStore.EditAnswer(ctx.Context(), revision); err != nil {
+		if errors.Is(err, db.ErrConflict) {
+			return ErrEditConflict()
+		}
+		return err
+	}
+
+	answer, err = h.answerStore.GetAnswerByID(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	renderAnswers(ctx.Context(), h.answerStore, answer)
+	if err := setAnswerVotes(ctx.Context(), h.voteStore, getOptionalUser(ctx), answer); err != nil {
+		return err
+	}
+
 	return ctx.JSON(answer)
-}
\ No newline at end of file
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/fullstack/dev-overflow/db"
+	"github.com/fullstack/dev-overflow/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+type BadgeHandler struct {
+	badgeStore db.BadgeStore
+	userStore db.UserStore
+}
+
+func NewBadgeHandler(badgeStore db.BadgeStore, userStore db.UserStore) *BadgeHandler {
+	return &BadgeHandler{
+		badgeStore: badgeStore,
+		userStore: userStore,
+	}
+}
+
+// HandleGetBadges lists the badge catalog with how many users hold each
+// badge.
+func (h *BadgeHandler) HandleGetBadges(ctx *fiber.Ctx) error {
+	counts, err := h.badgeStore.GetBadgeHolderCounts(ctx.Context())
+	if err != nil {
+		return err
+	}
+
+	badges := make([]*types.BadgeSummary, len(types.Badges))
+	for i, badge := range types.Badges {
+		badges[i] = &types.BadgeSummary{Badge: badge, HolderCount: counts[badge.ID]}
+	}
+
+	return ctx.JSON(badges)
+}
+
+func (h *BadgeHandler) HandleGetUserBadges(ctx *fiber.Ctx) error {
+	clerkID := ctx.Params("clerkID")
+
+	user, err := h.userStore.GetUserByID(ctx.Context(), clerkID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(clerkID)
+		}
+		return err
+	}
+
+	awards, err := h.badgeStore.GetUserBadges(ctx.Context(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(types.NewUserBadges(awards))
+}
+
+func (h *BadgeHandler) HandleGetRecentAwards(ctx *fiber.Ctx) error {
+	var params db.PageParams
+	if err := ctx.QueryParser(&params); err != nil {
+		return ErrBadRequest()
+	}
+
+	awards, err := h.badgeStore.GetRecentAwards(ctx.Context(), &params)
+	if err != nil {
+		return err
+	}
+
+	for _, award := range awards.Awards {
+		award.Badge = types.BadgeByID(award.BadgeID)
+	}
+
+	return ctx.JSON(awards)
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"errors"
+	"time"
+
+	"github.com/fullstack/dev-overflow/db"
+	"github.com/fullstack/dev-overflow/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+type BountyHandler struct {
+	bountyStore db.BountyStore
+	questionStore db.QuestionStore
+	answerStore db.AnswerStore
+}
+
+func NewBountyHandler(bountyStore db.BountyStore, questionStore db.QuestionStore, answerStore db.AnswerStore) *BountyHandler {
+	return &BountyHandler{
+		bountyStore: bountyStore,
+		questionStore: questionStore,
+		answerStore: answerStore,
+	}
+}
+
+func (h *BountyHandler) HandleCreateBounty(ctx *fiber.Ctx) error {
+	var (
+		id = ctx.Params("id")
+		params types.CreateBountyParams
+	)
+
+	if err := ctx.BodyParser(&params); err != nil {
+		return ErrBadRequest()
+	}
+
+	if errors := params.Validate(); len(errors) > 0 {
+		return ErrValidation(errors)
+	}
+
+	user, err := getAuthUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	question, err := h.questionStore.GetQuestionByID(ctx.Context(), id)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(id)
+		}
+		return err
+	}
+
+	if question.IsClosed() {
+		return ErrConflict("Closed questions cannot have a bounty").WithCode(CodeQuestionClosed)
+	}
+
+	if question.UserID != user.ID {
+		if err := requirePrivilege(user, types.PrivOfferBounty); err != nil {
+			return err
+		}
+	}
+
+	if user.Reputation < params.Amount {
+		return NewError(fiber.StatusForbidden, "Not enough reputation to offer this bounty")
+	}
+
+	now := time.Now().UTC()
+	bounty, err := h.bountyStore.CreateBounty(ctx.Context(), &types.Bounty{
+		QuestionID: question.ID,
+		SponsorID: user.ID,
+		Amount: params.Amount,
+		CreatedAt: now,
+		ExpiresAt: now.Add(types.BountyDuration),
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrBountyExists) {
+			return ErrConflict(err.Error())
+		}
+		if errors.Is(err, db.ErrInsufficientReputation) {
+			return NewError(fiber.StatusForbidden, "Not enough reputation to offer this bounty")
+		}
+		return err
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(bounty)
+}
+
+// HandleAwardBounty lets the sponsor pay the open bounty out to an answer
+// of the question before it expires.
+func (h *BountyHandler) HandleAwardBounty(ctx *fiber.Ctx) error {
+	var (
+		id = ctx.Params("id")
+		params types.AwardBountyParams
+	)
+
+	if err := ctx.BodyParser(&params); err != nil {
+		return ErrBadRequest()
+	}
+
+	if errors := params.Validate(); len(errors) > 0 {
+		return ErrValidation(errors)
+	}
+
+	user, err := getAuthUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	question, err := h.questionStore.GetQuestionByID(ctx.Context(), id)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(id)
+		}
+		return err
+	}
+
+	bounty, err := h.bountyStore.GetOpenBounty(ctx.Context(), question.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(id)
+		}
+		return err
+	}
+
+	if bounty.SponsorID != user.ID {
+		return ErrForbidden()
+	}
+
+	if !bounty.ExpiresAt.After(time.Now()) {
+		return ErrConflict("Bounty has expired")
+	}
+
+	answer, err := h.answerStore.GetAnswerByID(ctx.Context(), params.AnswerID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(params.AnswerID)
+		}
+		return err
+	}
+
+	if answer.QuestionID != question.ID {
+		return ErrBadRequest()
+	}
+
+	if answer.UserID == user.ID {
+		return NewError(fiber.StatusBadRequest, "You cannot award a bounty to your own answer")
+	}
+
+	bounty, err = h.bountyStore.AwardBounty(ctx.Context(), bounty, answer)
+	if err != nil {
+		if errors.Is(err, db.ErrBountyClosed) {
+			return ErrConflict(err.Error())
+		}
+		return err
+	}
+
+	return ctx.JSON(bounty)
+}
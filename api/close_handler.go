@@ -0,0 +1,197 @@
+package api
+
+import (
+	"errors"
+	"time"
+
+	"github.com/fullstack/dev-overflow/db"
+	"github.com/fullstack/dev-overflow/types"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CloseHandler runs the close and reopen workflow. Questions close or
+// reopen once voteThreshold privileged users voted, moderators act alone.
+type CloseHandler struct {
+	questionStore db.QuestionStore
+	voteThreshold int
+}
+
+func NewCloseHandler(questionStore db.QuestionStore, voteThreshold int) *CloseHandler {
+	return &CloseHandler{
+		questionStore: questionStore,
+		voteThreshold: voteThreshold,
+	}
+}
+
+func (h *CloseHandler) HandleCloseQuestion(ctx *fiber.Ctx) error {
+	var (
+		id = ctx.Params("id")
+		params types.CloseQuestionParams
+	)
+
+	if err := ctx.BodyParser(&params); err != nil {
+		return ErrBadRequest()
+	}
+
+	if errors := params.Validate(); len(errors) > 0 {
+		return ErrValidation(errors)
+	}
+
+	user, err := getAuthUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := requirePrivilege(user, types.PrivCloseVote); err != nil {
+		return err
+	}
+
+	question, err := h.getQuestion(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if question.IsClosed() {
+		return ErrConflict("Question is already closed")
+	}
+
+	if question.Bounty != nil {
+		return ErrConflict("Questions with an open bounty cannot be closed")
+	}
+
+	vote := &types.CloseVote{
+		UserID: user.ID,
+		Reason: params.Reason,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if params.Reason == types.CloseDuplicate {
+		original, err := h.resolveOriginal(ctx, question, params.DuplicateOf)
+		if err != nil {
+			return err
+		}
+		vote.DuplicateOf = &original
+	}
+
+	if user.Can(types.PermModeratePosts) {
+		if err := h.questionStore.CloseQuestion(ctx.Context(), question, vote.Reason, vote.DuplicateOf, []primitive.ObjectID{user.ID}); err != nil {
+			return h.conflictOr(err, "Question is already closed")
+		}
+		return h.respond(ctx, id)
+	}
+
+	if question.HasCloseVote(user.ID) {
+		return ErrConflict("You already voted to close this question")
+	}
+
+	question, err = h.questionStore.AddCloseVote(ctx.Context(), question.ID, vote)
+	if err != nil {
+		return h.conflictOr(err, "Question is already closed")
+	}
+
+	if len(question.CloseVotes) >= h.voteThreshold {
+		reason, original := types.CloseOutcome(question.CloseVotes)
+
+		closedBy := []primitive.ObjectID{}
+		for _, vote := range question.CloseVotes {
+			closedBy = append(closedBy, vote.UserID)
+		}
+
+		if err := h.questionStore.CloseQuestion(ctx.Context(), question, reason, original, closedBy); err != nil {
+			return h.conflictOr(err, "Question is already closed")
+		}
+	}
+
+	return h.respond(ctx, id)
+}
+
+func (h *CloseHandler) HandleReopenQuestion(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+
+	user, err := getAuthUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := requirePrivilege(user, types.PrivCloseVote); err != nil {
+		return err
+	}
+
+	question, err := h.getQuestion(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if !question.IsClosed() {
+		return ErrConflict("Question is not closed")
+	}
+
+	if !user.Can(types.PermModeratePosts) {
+		if question.HasReopenVote(user.ID) {
+			return ErrConflict("You already voted to reopen this question")
+		}
+
+		voted, err := h.questionStore.AddReopenVote(ctx.Context(), question.ID, user.ID)
+		if err != nil {
+			return h.conflictOr(err, "Question is not closed")
+		}
+
+		if len(voted.ReopenVotes) < h.voteThreshold {
+			return h.respond(ctx, id)
+		}
+	}
+
+	if err := h.questionStore.ReopenQuestion(ctx.Context(), question); err != nil {
+		return h.conflictOr(err, "Question is not closed")
+	}
+
+	return h.respond(ctx, id)
+}
+
+// resolveOriginal returns the canonical question a duplicate should link
+// to, following the original when it is itself a duplicate.
+func (h *CloseHandler) resolveOriginal(ctx *fiber.Ctx, question *types.Question, id string) (primitive.ObjectID, error) {
+	original, err := h.getQuestion(ctx, id)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	canonical := original.ID
+	if original.DuplicateOf != nil {
+		canonical = *original.DuplicateOf
+	}
+
+	if canonical == question.ID {
+		return primitive.NilObjectID, NewError(fiber.StatusBadRequest, "A question cannot be a duplicate of itself")
+	}
+
+	return canonical, nil
+}
+
+func (h *CloseHandler) getQuestion(ctx *fiber.Ctx, id string) (*types.Question, error) {
+	question, err := h.questionStore.GetQuestionByID(ctx.Context(), id)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, ErrResourceNotFound(id)
+		}
+		return nil, err
+	}
+	return question, nil
+}
+
+// conflictOr maps the store's lost race to a 409.
+func (h *CloseHandler) conflictOr(err error, msg string) error {
+	if errors.Is(err, db.ErrNotFound) {
+		return ErrConflict(msg)
+	}
+	return err
+}
+
+func (h *CloseHandler) respond(ctx *fiber.Ctx, id string) error {
+	question, err := h.questionStore.GetQuestionByID(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(question)
+}
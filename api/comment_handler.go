@@ -0,0 +1,399 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/fullstack/dev-overflow/db"
+	"github.com/fullstack/dev-overflow/types"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type CommentHandler struct {
+	commentStore db.CommentStore
+	questionStore db.QuestionStore
+	answerStore db.AnswerStore
+	interactionStore db.InteractionStore
+	quotaStore db.QuotaStore
+}
+
+func NewCommentHandler(commentStore db.CommentStore, questionStore db.QuestionStore, answerStore db.AnswerStore, interactionStore db.InteractionStore, quotaStore db.QuotaStore) *CommentHandler {
+	return &CommentHandler{
+		commentStore: commentStore,
+		questionStore: questionStore,
+		answerStore: answerStore,
+		interactionStore: interactionStore,
+		quotaStore: quotaStore,
+	}
+}
+
+// commentPost is the question or answer a comment thread hangs off.
+type commentPost struct {
+	postType string
+	id primitive.ObjectID
+	question *types.Question
+	owner *types.PublicUser
+}
+
+func (h *CommentHandler) HandleCreateQuestionComment(ctx *fiber.Ctx) error {
+	return h.createComment(ctx, types.CommentOnQuestion)
+}
+
+func (h *CommentHandler) HandleCreateAnswerComment(ctx *fiber.Ctx) error {
+	return h.createComment(ctx, types.CommentOnAnswer)
+}
+
+func (h *CommentHandler) HandleGetQuestionComments(ctx *fiber.Ctx) error {
+	return h.getComments(ctx, types.CommentOnQuestion)
+}
+
+func (h *CommentHandler) HandleGetAnswerComments(ctx *fiber.Ctx) error {
+	return h.getComments(ctx, types.CommentOnAnswer)
+}
+
+func (h *CommentHandler) HandleEditComment(ctx *fiber.Ctx) error {
+	var (
+		id = ctx.Params("id")
+		params types.CommentParams
+	)
+
+	if err := ctx.BodyParser(&params); err != nil {
+		return ErrBadRequest()
+	}
+
+	if errors := params.Validate(); len(errors) > 0 {
+		return ErrValidation(errors)
+	}
+
+	user, err := getAuthUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	comment, err := h.getComment(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	if !comment.CanEdit(user, now) {
+		return NewError(fiber.StatusForbidden, "Comments can only be edited by their author in the first 5 minutes")
+	}
+
+	post, err := h.resolvePost(ctx, comment.PostType, comment.PostID.Hex())
+	if err != nil {
+		return err
+	}
+
+	mentions, err := h.resolveMentions(ctx, post, params.Content, user.ID)
+	if err != nil {
+		return err
+	}
+
+	mentioned := make(map[primitive.ObjectID]bool, len(comment.Mentions))
+	for _, userID := range comment.Mentions {
+		mentioned[userID] = true
+	}
+
+	comment.Content = params.Content
+	comment.Mentions = mentions
+	comment.EditedAt = &now
+
+	if err := h.commentStore.EditComment(ctx.Context(), comment); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(id)
+		}
+		return err
+	}
+
+	added := []primitive.ObjectID{}
+	for _, userID := range mentions {
+		if !mentioned[userID] {
+			added = append(added, userID)
+		}
+	}
+
+	if err := h.recordMentions(ctx, post, added); err != nil {
+		return err
+	}
+
+	return ctx.JSON(comment)
+}
+
+func (h *CommentHandler) HandleDeleteComment(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+
+	user, err := getAuthUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	comment, err := h.getComment(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if !types.Authorize(user, types.ActionDeletePost, comment.UserID) {
+		return ErrForbidden()
+	}
+
+	if err := h.commentStore.DeleteComment(ctx.Context(), comment.ID); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(id)
+		}
+		return err
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Comment deleted", "id": id})
+}
+
+func (h *CommentHandler) HandleUpvoteComment(ctx *fiber.Ctx) error {
+	return h.setUpvote(ctx, true)
+}
+
+func (h *CommentHandler) HandleRemoveCommentUpvote(ctx *fiber.Ctx) error {
+	return h.setUpvote(ctx, false)
+}
+
+func (h *CommentHandler) createComment(ctx *fiber.Ctx, postType string) error {
+	var (
+		id = ctx.Params("id")
+		params types.CommentParams
+	)
+
+	if err := ctx.BodyParser(&params); err != nil {
+		return ErrBadRequest()
+	}
+
+	if errors := params.Validate(); len(errors) > 0 {
+		return ErrValidation(errors)
+	}
+
+	user, err := getAuthUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	post, err := h.resolvePost(ctx, postType, id)
+	if err != nil {
+		return err
+	}
+
+	// Everyone may comment on their own posts.
+	if post.owner.ID != user.ID {
+		if err := requirePrivilege(user, types.PrivComment); err != nil {
+			return err
+		}
+	}
+
+	mentions, err := h.resolveMentions(ctx, post, params.Content, user.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := consumeQuota(ctx.Context(), h.quotaStore, user, types.QuotaComments); err != nil {
+		return err
+	}
+
+	comment, err := h.commentStore.CreateComment(ctx.Context(), &types.Comment{
+		PostID: post.id,
+		PostType: postType,
+		QuestionID: post.question.ID,
+		UserID: user.ID,
+		Content: params.Content,
+		Upvotes: []primitive.ObjectID{},
+		Mentions: mentions,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		if err := refundQuota(ctx.Context(), h.quotaStore, user, types.QuotaComments); err != nil {
+			return err
+		}
+		return err
+	}
+
+	if err := h.recordMentions(ctx, post, mentions); err != nil {
+		return err
+	}
+
+	comment.User = user.Public()
+
+	return ctx.Status(fiber.StatusCreated).JSON(comment)
+}
+
+// getComments is the "show more" listing of every comment on a post.
+func (h *CommentHandler) getComments(ctx *fiber.Ctx, postType string) error {
+	var (
+		id = ctx.Params("id")
+		params db.PageParams
+	)
+
+	if err := ctx.QueryParser(&params); err != nil {
+		return ErrBadRequest()
+	}
+
+	post, err := h.resolvePost(ctx, postType, id)
+	if err != nil {
+		return err
+	}
+
+	comments, err := h.commentStore.GetComments(ctx.Context(), post.id, &params)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(comments)
+}
+
+func (h *CommentHandler) setUpvote(ctx *fiber.Ctx, upvote bool) error {
+	id := ctx.Params("id")
+
+	user, err := getAuthUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	comment, err := h.getComment(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if comment.UserID == user.ID {
+		return NewError(fiber.StatusForbidden, "You cannot upvote your own comment")
+	}
+
+	if upvote {
+		err = h.commentStore.UpvoteComment(ctx.Context(), comment.ID, user.ID)
+	} else {
+		err = h.commentStore.RemoveCommentUpvote(ctx.Context(), comment.ID, user.ID)
+	}
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(id)
+		}
+		return err
+	}
+
+	comment, err = h.getComment(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(comment)
+}
+
+func (h *CommentHandler) resolvePost(ctx *fiber.Ctx, postType, id string) (*commentPost, error) {
+	if postType == types.CommentOnAnswer {
+		answer, err := h.answerStore.GetAnswerByID(ctx.Context(), id)
+		if err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				return nil, ErrResourceNotFound(id)
+			}
+			return nil, err
+		}
+
+		question, err := h.questionStore.GetQuestionByID(ctx.Context(), answer.QuestionID.Hex())
+		if err != nil {
+			return nil, err
+		}
+
+		return &commentPost{postType: postType, id: answer.ID, question: question, owner: answer.User}, nil
+	}
+
+	question, err := h.questionStore.GetQuestionByID(ctx.Context(), id)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, ErrResourceNotFound(id)
+		}
+		return nil, err
+	}
+
+	return &commentPost{postType: postType, id: question.ID, question: question, owner: question.User}, nil
+}
+
+// resolveMentions finds the thread participants, the post owner and
+// everyone who commented on it, mentioned in content.
+func (h *CommentHandler) resolveMentions(ctx *fiber.Ctx, post *commentPost, content string, authorID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	participants, err := h.commentStore.GetCommenters(ctx.Context(), post.id)
+	if err != nil {
+		return nil, err
+	}
+	participants = append(participants, post.owner)
+
+	mentions := []primitive.ObjectID{}
+	for _, userID := range types.ResolveMentions(content, participants) {
+		if userID != authorID {
+			mentions = append(mentions, userID)
+		}
+	}
+
+	return mentions, nil
+}
+
+func (h *CommentHandler) recordMentions(ctx *fiber.Ctx, post *commentPost, userIDs []primitive.ObjectID) error {
+	var answerID primitive.ObjectID
+	if post.postType == types.CommentOnAnswer {
+		answerID = post.id
+	}
+
+	for _, userID := range userIDs {
+		interaction := &types.Interaction{
+			UserID: userID,
+			Action: types.InteractionMention,
+			QuestionID: post.question.ID,
+			AnswerID: answerID,
+			Tags: post.question.Tags,
+			CreatedAt: time.Now().UTC(),
+		}
+		if _, err := h.interactionStore.CreateInteraction(ctx.Context(), interaction); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (h *CommentHandler) getComment(ctx *fiber.Ctx, id string) (*types.Comment, error) {
+	comment, err := h.commentStore.GetCommentByID(ctx.Context(), id)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, ErrResourceNotFound(id)
+		}
+		return nil, err
+	}
+	return comment, nil
+}
+
+// embedQuestionComments attaches the top comments of a question.
+func embedQuestionComments(ctx context.Context, store db.CommentStore, question *types.Question) error {
+	top, err := store.GetTopComments(ctx, []primitive.ObjectID{question.ID}, types.TopCommentsLimit)
+	if err != nil {
+		return err
+	}
+
+	question.Comments = top[question.ID]
+
+	return nil
+}
+
+// embedAnswerComments attaches the top comments of each answer with a
+// single query.
+func embedAnswerComments(ctx context.Context, store db.CommentStore, answers []*types.Answer) error {
+	ids := make([]primitive.ObjectID, len(answers))
+	for i, answer := range answers {
+		ids[i] = answer.ID
+	}
+
+	top, err := store.GetTopComments(ctx, ids, types.TopCommentsLimit)
+	if err != nil {
+		return err
+	}
+
+	for _, answer := range answers {
+		answer.Comments = top[answer.ID]
+	}
+
+	return nil
+}
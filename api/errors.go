@@ -1,55 +1,154 @@
 package api
 
-import "github.com/gofiber/fiber/v2"
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
 
+	"github.com/fullstack/dev-overflow/db"
+	"github.com/fullstack/dev-overflow/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+// Stable error codes clients can branch on. NewError derives the code from
+// the status for everything else.
+const (
+	CodeInvalidRequest = "invalid_request"
+	CodeInvalidID = "invalid_id"
+	CodeValidationFailed = "validation_failed"
+	CodeUnauthorized = "unauthorized"
+	CodeForbidden = "forbidden"
+	CodeNotFound = "not_found"
+	CodeConflict = "conflict"
+	CodeQuestionClosed = "question_closed"
+	CodePossibleDuplicate = "possible_duplicate"
+	CodePrivilegeRequired = "privilege_required"
+	CodeQuotaExceeded = "quota_exceeded"
+	CodeInternal = "internal_error"
+)
+
+// Error is an RFC 7807 problem details object. Instance and RequestID are
+// filled in by ErrorHandler.
 type Error struct {
-	Code int `json:"code"`
-	Message string `json:"message"`
+	Type string `json:"type"`
+	Title string `json:"title"`
+	Status int `json:"status"`
+	Code string `json:"code"`
+	Detail string `json:"detail"`
+	Instance string `json:"instance,omitempty"`
+	RequestID string `json:"requestID,omitempty"`
+	Errors types.ValidationErrors `json:"errors,omitempty"`
+	Duplicates []*types.SimilarQuestion `json:"duplicates,omitempty"`
+	Privilege types.Privilege `json:"privilege,omitempty"`
 }
 
-func NewError(code int, message string) Error {
+func NewError(status int, detail string) Error {
 	return Error{
-		Code: code,
-		Message: message,
+		Type: "about:blank",
+		Title: http.StatusText(status),
+		Status: status,
+		Code: strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_"),
+		Detail: detail,
 	}
 }
 
 func (e Error) Error() string {
-	return e.Message
+	return e.Detail
+}
+
+func (e Error) WithCode(code string) Error {
+	e.Code = code
+	return e
 }
 
+// ErrorHandler writes every error as application/problem+json. Store errors
+// are mapped by kind and anything unknown becomes a 500 without leaking its
+// message, which is logged with the request ID instead.
 func ErrorHandler(ctx *fiber.Ctx, err error) error {
-	if apiError, ok := err.(Error); ok {
-		return ctx.Status(apiError.Code).JSON(apiError)
+	problem := problemFromError(err)
+	problem.Instance = ctx.OriginalURL()
+	problem.RequestID = ctx.GetRespHeader(fiber.HeaderXRequestID)
+
+	if problem.Status >= fiber.StatusInternalServerError {
+		log.Printf("request %s %s %s: %v", problem.RequestID, ctx.Method(), ctx.Path(), err)
 	}
-	apiError := NewError(fiber.StatusInternalServerError, err.Error())
-	return ctx.Status(apiError.Code).JSON(apiError)
+
+	return ctx.Status(problem.Status).JSON(problem, MIMEApplicationProblemJSON)
 }
 
-func ErrInvalidID() Error {
-	return Error{
-		Code: fiber.StatusBadRequest,
-		Message: "Invalid ID",
+func problemFromError(err error) Error {
+	var (
+		apiError Error
+		fiberError *fiber.Error
+	)
+
+	switch {
+	case errors.As(err, &apiError):
+		return apiError
+	case errors.Is(err, db.ErrNotFound):
+		return NewError(fiber.StatusNotFound, "Resource Not Found").WithCode(CodeNotFound)
+	case errors.Is(err, db.ErrInvalidID):
+		return ErrInvalidID()
+	case errors.Is(err, db.ErrConflict):
+		return ErrConflict("Resource Conflicts With Its Current State")
+	case errors.Is(err, db.ErrForbidden):
+		return ErrForbidden()
+	case errors.As(err, &fiberError):
+		return NewError(fiberError.Code, fiberError.Message)
 	}
+
+	return NewError(fiber.StatusInternalServerError, "Internal Server Error").WithCode(CodeInternal)
+}
+
+func ErrInvalidID() Error {
+	return NewError(fiber.StatusBadRequest, "Invalid ID").WithCode(CodeInvalidID)
 }
 
 func ErrUnauthorized() Error {
-	return Error{
-		Code: fiber.StatusUnauthorized,
-		Message: "Unauthorized User",
-	}
+	return NewError(fiber.StatusUnauthorized, "Unauthorized User").WithCode(CodeUnauthorized)
 }
 
 func ErrBadRequest() Error {
-	return Error{
-		Code: fiber.StatusBadRequest,
-		Message: "Invalid JSON Request",
-	}
+	return NewError(fiber.StatusBadRequest, "Invalid JSON Request").WithCode(CodeInvalidRequest)
 }
 
 func ErrResourceNotFound(res string) Error {
-	return Error{
-		Code: fiber.StatusNotFound,
-		Message: res + " Resource Not Found",
-	}
-}
\ No newline at end of file
+	return NewError(fiber.StatusNotFound, res+" Resource Not Found").WithCode(CodeNotFound)
+}
+
+func ErrForbidden() Error {
+	return NewError(fiber.StatusForbidden, "Forbidden").WithCode(CodeForbidden)
+}
+
+func ErrConflict(detail string) Error {
+	return NewError(fiber.StatusConflict, detail).WithCode(CodeConflict)
+}
+
+// ErrEditConflict is returned to the loser of two concurrent edits of the
+// same post.
+func ErrEditConflict() Error {
+	return ErrConflict("Post was edited by someone else, reload it and try again")
+}
+
+// ErrPrivilegeRequired names the privilege the user's reputation has not
+// earned yet.
+func ErrPrivilegeRequired(user *types.User, priv types.Privilege) Error {
+	problem := NewError(fiber.StatusForbidden, types.PrivilegeRuleOf(priv).Message(user)).WithCode(CodePrivilegeRequired)
+	problem.Privilege = priv
+	return problem
+}
+
+func ErrQuotaExceeded(kind types.QuotaKind, limit int) Error {
+	detail := fmt.Sprintf("Daily limit of %d %s reached, it resets at midnight UTC", limit, kind)
+	return NewError(fiber.StatusTooManyRequests, detail).WithCode(CodeQuotaExceeded)
+}
+
+func ErrValidation(errors types.ValidationErrors) Error {
+	problem := NewError(fiber.StatusUnprocessableEntity, "Validation Failed").WithCode(CodeValidationFailed)
+	problem.Errors = errors
+	return problem
+}
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"errors"
+
 	"github.com/fullstack/dev-overflow/db"
 	"github.com/fullstack/dev-overflow/types"
 	"github.com/gofiber/fiber/v2"
@@ -8,20 +10,53 @@ import (
 
 type InteractionHandler struct {
 	interactionStore db.InteractionStore
+	userStore db.UserStore
 }
 
-func NewInteractionHandler(interactionStore db.InteractionStore) *InteractionHandler {
+func NewInteractionHandler(interactionStore db.InteractionStore, userStore db.UserStore) *InteractionHandler {
 	return &InteractionHandler{
 		interactionStore: interactionStore,
+		userStore: userStore,
 	}
 }
 
+func (h *InteractionHandler) HandleGetUserActivity(ctx *fiber.Ctx) error {
+	var (
+		clerkID = ctx.Params("clerkID")
+	)
+
+	user, err := h.userStore.GetUserByID(ctx.Context(), clerkID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(clerkID)
+		}
+		return err
+	}
+
+	interactions, err := h.interactionStore.GetInteractionsByUserID(ctx.Context(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(interactions)
+}
+
 func (h *InteractionHandler) HandleCreateViewInteraction(ctx *fiber.Ctx) error {
 	var params types.ViewQuestionParams
 
 	if err := ctx.BodyParser(&params); err != nil {
+		return ErrBadRequest()
+	}
+
+	if errors := params.Validate(); len(errors) > 0 {
+		return ErrValidation(errors)
+	}
+
+	user, err := getAuthUser(ctx)
+	if err != nil {
 		return err
 	}
+	params.UserID = user.ClerkID
 
 	interaction, err := h.interactionStore.CreateViewInteraction(ctx.Context(), &params)
 	if err != nil {
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clerkinc/clerk-sdk-go/clerk"
+	"github.com/fullstack/dev-overflow/db"
+	"github.com/fullstack/dev-overflow/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+type fakeTokenVerifier map[string]string
+
+func (v fakeTokenVerifier) VerifyToken(token string, _ ...clerk.VerifyTokenOption) (*clerk.SessionClaims, error) {
+	subject, ok := v[token]
+	if !ok {
+		return nil, errors.New("invalid token")
+	}
+
+	claims := &clerk.SessionClaims{}
+	claims.Subject = subject
+	return claims, nil
+}
+
+// fakeUserStore only implements the lookups the middleware and webhook tests
+// need, the embedded interface panics on anything else.
+type fakeUserStore struct {
+	db.UserStore
+	users map[string]*types.User
+}
+
+func (s *fakeUserStore) GetUserByID(_ context.Context, clerkID string) (*types.User, error) {
+	user, ok := s.users[clerkID]
+	if !ok {
+		return nil, db.ErrNotFound
+	}
+	return user, nil
+}
+
+func TestJWTAuthentication(t *testing.T) {
+	verifier := fakeTokenVerifier{
+		"valid": "user_1",
+		"unknown": "user_404",
+	}
+	userStore := &fakeUserStore{users: map[string]*types.User{
+		"user_1": {ClerkID: "user_1"},
+	}}
+
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use(JWTAuthentication(verifier, userStore))
+	app.All("/", func(ctx *fiber.Ctx) error {
+		if user := getOptionalUser(ctx); user != nil {
+			return ctx.SendString(user.ClerkID)
+		}
+		return ctx.SendString("anonymous")
+	})
+
+	tests := []struct {
+		name string
+		method string
+		token string
+		status int
+		body string
+	}{
+		{name: "anonymous read", method: fiber.MethodGet, status: fiber.StatusOK, body: "anonymous"},
+		{name: "anonymous write", method: fiber.MethodPost, status: fiber.StatusUnauthorized},
+		{name: "invalid token", method: fiber.MethodGet, token: "forged", status: fiber.StatusUnauthorized},
+		{name: "unknown subject", method: fiber.MethodPost, token: "unknown", status: fiber.StatusUnauthorized},
+		{name: "valid token", method: fiber.MethodPost, token: "valid", status: fiber.StatusOK, body: "user_1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", nil)
+			if tt.token != "" {
+				req.Header.Set(fiber.HeaderAuthorization, "Bearer "+tt.token)
+			}
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, resp.StatusCode)
+			}
+
+			if tt.body == "" {
+				return
+			}
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(body) != tt.body {
+				t.Fatalf("expected body %q, got %q", tt.body, body)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"github.com/fullstack/dev-overflow/types"
 	"github.com/gofiber/fiber/v2"
 	openai "github.com/sashabaranov/go-openai"
 )
@@ -18,12 +19,12 @@ func NewOpenAIHandler(client *openai.Client) *OpenAIHandler {
 func (h *OpenAIHandler) HandleChatGPT(ctx *fiber.Ctx) error {
 	var reqBody map[string]string
 	if err := ctx.BodyParser(&reqBody); err != nil {
-		return err
+		return ErrBadRequest()
 	}
 
 	question, ok := reqBody["description"]
 	if !ok {
-		return fiber.NewError(fiber.StatusBadRequest, "missing question")
+		return ErrValidation(types.ValidationErrors{"description": "Description is required"})
 	}
 
 	resp, err := h.Client.CreateChatCompletion(
@@ -44,7 +45,7 @@ func (h *OpenAIHandler) HandleChatGPT(ctx *fiber.Ctx) error {
 	)
 
 	if err != nil {
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 
 	reply := resp.Choices[0].Message.Content
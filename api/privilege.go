@@ -0,0 +1,42 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/fullstack/dev-overflow/db"
+	"github.com/fullstack/dev-overflow/types"
+)
+
+// requirePrivilege rejects users whose reputation has not earned priv.
+func requirePrivilege(user *types.User, priv types.Privilege) error {
+	if user.HasPrivilege(priv) {
+		return nil
+	}
+	return ErrPrivilegeRequired(user, priv)
+}
+
+// consumeQuota counts one kind action of the user against their daily
+// quota, it must run once every other check passed.
+func consumeQuota(ctx context.Context, store db.QuotaStore, user *types.User, kind types.QuotaKind) error {
+	limit := user.DailyQuota(kind)
+	if limit < 0 {
+		return nil
+	}
+
+	err := store.ConsumeQuota(ctx, user.ID, kind, limit)
+	if errors.Is(err, db.ErrQuotaExceeded) {
+		return ErrQuotaExceeded(kind, limit)
+	}
+
+	return err
+}
+
+// refundQuota gives back the kind action consumeQuota counted when the
+// action failed after all.
+func refundQuota(ctx context.Context, store db.QuotaStore, user *types.User, kind types.QuotaKind) error {
+	if user.DailyQuota(kind) < 0 {
+		return nil
+	}
+	return store.RefundQuota(ctx, user.ID, kind)
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"time"
+
+	"github.com/fullstack/dev-overflow/db"
+	"github.com/fullstack/dev-overflow/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+type PrivilegeHandler struct {
+	quotaStore db.QuotaStore
+}
+
+func NewPrivilegeHandler(quotaStore db.QuotaStore) *PrivilegeHandler {
+	return &PrivilegeHandler{
+		quotaStore: quotaStore,
+	}
+}
+
+// HandleGetPrivileges lists the caller's privileges, earned or not, and
+// what is left of their daily quotas.
+func (h *PrivilegeHandler) HandleGetPrivileges(ctx *fiber.Ctx) error {
+	user, err := getAuthUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	used, err := h.quotaStore.GetQuotaUsage(ctx.Context(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(types.NewUserPrivileges(user, used, time.Now().UTC()))
+}
@@ -2,7 +2,7 @@ package api
 
 import (
 	"errors"
-	"log"
+	"fmt"
 	"time"
 
 	"github.com/fullstack/dev-overflow/db"
@@ -10,22 +10,35 @@ import (
 	"github.com/fullstack/dev-overflow/utils"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
+const similarQuestionLimit = 5
+
 type QuestionHandler struct {
 	questionStore db.QuestionStore
 	userStore db.UserStore
 	tagStore db.TagStore
 	answerStore db.AnswerStore
+	revisionStore db.RevisionStore
+	reputationStore db.ReputationStore
+	searchStore db.SearchStore
+	commentStore db.CommentStore
+	voteStore db.VoteStore
+	quotaStore db.QuotaStore
 }
 
-func NewQuestionHandler(questionStore db.QuestionStore, userStore db.UserStore, tagStore db.TagStore, answerStore db.AnswerStore) *QuestionHandler {
+func NewQuestionHandler(questionStore db.QuestionStore, userStore db.UserStore, tagStore db.TagStore, answerStore db.AnswerStore, revisionStore db.RevisionStore, reputationStore db.ReputationStore, searchStore db.SearchStore, commentStore db.CommentStore, voteStore db.VoteStore, quotaStore db.QuotaStore) *QuestionHandler {
 	return &QuestionHandler{
 		questionStore: questionStore,
 		userStore: userStore,
 		tagStore: tagStore,
 		answerStore: answerStore,
+		revisionStore: revisionStore,
+		reputationStore: reputationStore,
+		searchStore: searchStore,
+		commentStore: commentStore,
+		voteStore: voteStore,
+		quotaStore: quotaStore,
 	}
 }
 
@@ -36,70 +49,128 @@ func (h *QuestionHandler) HandleGetQuestionByID(ctx *fiber.Ctx) error {
 	
 	question, err := h.questionStore.GetQuestionByID(ctx.Context(), id)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
+		if errors.Is(err, db.ErrNotFound) {
 			return ErrResourceNotFound(id)
 		}
 		return err
 	}
 
+	renderQuestions(ctx.Context(), h.questionStore, question)
+	if err := setQuestionVotes(ctx.Context(), h.voteStore, getOptionalUser(ctx), question); err != nil {
+		return err
+	}
+
+	if err := embedQuestionComments(ctx.Context(), h.commentStore, question); err != nil {
+		return err
+	}
+
 	return ctx.JSON(question)
 }
 
 func (h *QuestionHandler) HandleGetQuestionsByUserID(ctx *fiber.Ctx) error {
 	var (
 		id = ctx.Params("id")
+		params db.QuestionQueryParams
 	)
 
-	questions, err := h.questionStore.GetQuestionsByUserID(ctx.Context(), id)
+	if err := ctx.QueryParser(&params); err != nil {
+		return ErrBadRequest()
+	}
+
+	questions, err := h.questionStore.GetQuestionsByUserID(ctx.Context(), id, &params)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
+		if errors.Is(err, db.ErrNotFound) {
 			return ErrResourceNotFound(id)
 		}
 		return err
 	}
 
+	renderQuestions(ctx.Context(), h.questionStore, questions.Questions...)
+	if err := setQuestionVotes(ctx.Context(), h.voteStore, getOptionalUser(ctx), questions.Questions...); err != nil {
+		return err
+	}
+
 	return ctx.JSON(questions)
 }
 
 func (h *QuestionHandler) HandleGetQuestions(ctx *fiber.Ctx) error {
-	questions, err := h.questionStore.GetQuestions(ctx.Context())
+	var params db.QuestionQueryParams
+
+	if err := ctx.QueryParser(&params); err != nil {
+		return ErrBadRequest()
+	}
+
+	questions, err := h.questionStore.GetQuestions(ctx.Context(), &params)
 	if err != nil {
-		return ErrResourceNotFound("question")
+		return err
+	}
+
+	renderQuestions(ctx.Context(), h.questionStore, questions.Questions...)
+	if err := setQuestionVotes(ctx.Context(), h.voteStore, getOptionalUser(ctx), questions.Questions...); err != nil {
+		return err
 	}
 
 	return ctx.JSON(questions)
 }
 
+// HandleGetSavedQuestions lists a user's saved questions, which are private
+// to the owner and to users who may view private fields.
 func (h *QuestionHandler) HandleGetSavedQuestions(ctx *fiber.Ctx) error {
 	var (
 		id = ctx.Params("clerkID")
-		// params types.SavedQuestionQueryParams
+		params db.QuestionQueryParams
 	)
 
-	// if err := ctx.BodyParser(&params); err != nil {
-	// 	return ErrBadRequest()
-	// }
+	viewer, err := getAuthUser(ctx)
+	if err != nil {
+		return err
+	}
 
-	// questions, err := h.questionStore.GetSavedQuestions(ctx.Context(), id, &params)
-	questions, err := h.questionStore.GetSavedQuestions(ctx.Context(), id)
+	if viewer.ClerkID != id && !viewer.Can(types.PermViewPrivateFields) {
+		return ErrForbidden()
+	}
+
+	if err := ctx.QueryParser(&params); err != nil {
+		return ErrBadRequest()
+	}
+
+	questions, err := h.questionStore.GetSavedQuestions(ctx.Context(), id, &params)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
+		if errors.Is(err, db.ErrNotFound) {
 			return ErrResourceNotFound(id)
 		}
 		return err
 	}
 
+	renderQuestions(ctx.Context(), h.questionStore, questions.Questions...)
+	if err := setQuestionVotes(ctx.Context(), h.voteStore, getOptionalUser(ctx), questions.Questions...); err != nil {
+		return err
+	}
+
 	return ctx.JSON(questions)
 }
 
 func (h *QuestionHandler) HandleGetQuestiosByTagID (ctx *fiber.Ctx) error {
 	var (
 		id = ctx.Params("id")
+		params db.QuestionQueryParams
 	)
 
-	questions, err := h.questionStore.GetQuestionsByTagID(ctx.Context(), id)
+	if err := ctx.QueryParser(&params); err != nil {
+		return ErrBadRequest()
+	}
+
+	questions, err := h.questionStore.GetQuestionsByTagID(ctx.Context(), id, &params)
 	if err != nil {
-		return ErrResourceNotFound(id)
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(id)
+		}
+		return err
+	}
+
+	renderQuestions(ctx.Context(), h.questionStore, questions.Questions...)
+	if err := setQuestionVotes(ctx.Context(), h.voteStore, getOptionalUser(ctx), questions.Questions...); err != nil {
+		return err
 	}
 
 	return ctx.JSON(questions)
@@ -113,7 +184,7 @@ func (h *QuestionHandler) HandleAskQuestion(ctx *fiber.Ctx) error {
 	}
 
 	if errors := params.Validate(); len(errors) > 0 {
-		return ctx.JSON(errors)
+		return ErrValidation(errors)
 	}
 
 	for i, tag := range params.Tags {
@@ -121,35 +192,35 @@ func (h *QuestionHandler) HandleAskQuestion(ctx *fiber.Ctx) error {
 		params.Tags[i] = tag
 	}
 
-	user, err := h.userStore.GetUserByID(ctx.Context(), params.ClerkID)
+	user, err := getAuthUser(ctx)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return ErrResourceNotFound(params.ClerkID)
-		}
+		return err
 	}
 
-	tags := make([]primitive.ObjectID, len(params.Tags))
-	for i, tagName := range params.Tags {
-
-		tag, err := h.tagStore.GetTagByName(ctx.Context(), tagName)
+	if !params.NotDuplicate {
+		duplicates, err := h.nearDuplicates(ctx, &types.QuestionDraft{
+			Title: params.Title,
+			Description: params.Description,
+			Tags: params.Tags,
+		})
 		if err != nil {
-			if errors.Is(err, mongo.ErrNoDocuments) {
-				tag = &types.Tag{
-					Name: tagName,
-					Questions: []primitive.ObjectID{},
-					Followers: []primitive.ObjectID{},
-					CreatedAt: time.Now().UTC(),
-				}
-
-				insertedTag, err := h.tagStore.CreateTag(ctx.Context(), tag)
-				if err != nil {
-					return ErrBadRequest()
-				}
-
-				tag = insertedTag
-			} 
+			return err
+		}
+
+		if len(duplicates) > 0 {
+			problem := ErrConflict("Similar questions already exist, set notDuplicate to ask anyway").WithCode(CodePossibleDuplicate)
+			problem.Duplicates = duplicates
+			return problem
 		}
-		tags[i] = *&tag.ID
+	}
+
+	tags, err := h.resolveTags(ctx, user, params.Tags)
+	if err != nil {
+		return err
+	}
+
+	if err := consumeQuota(ctx.Context(), h.quotaStore, user, types.QuotaQuestions); err != nil {
+		return err
 	}
 
 	question := &types.Question{
@@ -157,113 +228,394 @@ func (h *QuestionHandler) HandleAskQuestion(ctx *fiber.Ctx) error {
 		Description: params.Description,
 		UserID: user.ID,
 		Tags: tags,
-		Upvotes: []primitive.ObjectID{},
-		Downvotes: []primitive.ObjectID{},
-		Answers: []primitive.ObjectID{},
 		CreatedAt: time.Now().UTC(),
 	}
 
 
 	insertedQuestion, err := h.questionStore.AskQuestion(ctx.Context(), question)
 	if err != nil {
-		log.Println(err)
-			return ErrBadRequest()
+		if err := refundQuota(ctx.Context(), h.quotaStore, user, types.QuotaQuestions); err != nil {
+			return err
 		}
+		return err
+	}
 
 	return ctx.JSON(insertedQuestion)
 }
 
-func (h *QuestionHandler) HandleDeleteQuestionByID(ctx *fiber.Ctx) error {
+// HandleGetSimilarQuestions suggests existing questions for a draft so the
+// asker can check for duplicates before posting.
+func (h *QuestionHandler) HandleGetSimilarQuestions(ctx *fiber.Ctx) error {
+	var draft types.QuestionDraft
+
+	if err := ctx.BodyParser(&draft); err != nil {
+		return ErrBadRequest()
+	}
+
+	if errors := draft.Validate(); len(errors) > 0 {
+		return ErrValidation(errors)
+	}
+
+	similar, err := h.searchStore.SimilarQuestions(ctx.Context(), &draft, similarQuestionLimit)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(similar)
+}
+
+func (h *QuestionHandler) HandleEditQuestion(ctx *fiber.Ctx) error {
 	var (
-		id = ctx.Params("_id")
-		params types.DeleteQuestionParams
+		id = ctx.Params("id")
+		params types.EditQuestionParams
 	)
 
 	if err := ctx.BodyParser(&params); err != nil {
 		return ErrBadRequest()
 	}
 
-	user, err := h.userStore.GetUserByID(ctx.Context(), params.UserID)
+	user, err := getAuthUser(ctx)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return ErrResourceNotFound(params.UserID)
-		}
+		return err
 	}
 
 	question, err := h.questionStore.GetQuestionByID(ctx.Context(), id)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
+		if errors.Is(err, db.ErrNotFound) {
 			return ErrResourceNotFound(id)
 		}
+		return err
+	}
+
+	if !types.Authorize(user, types.ActionEditPost, question.UserID) {
+		return ErrForbidden()
+	}
+
+	if errors := params.Validate(); len(errors) > 0 {
+		return ErrValidation(errors)
+	}
+
+	for i, tag := range params.Tags {
+		params.Tags[i] = utils.FormatTag(tag)
 	}
 
-	for _, answerID := range question.Answers {
-		if err := h.answerStore.DeleteAnswerByID(ctx.Context(), answerID.Hex()); err != nil {
-			return ErrBadRequest()
+	revisions, err := h.revisionStore.GetQuestionRevisions(ctx.Context(), question.ID)
+	if err != nil {
+		return err
+	}
+
+	// Questions asked before revisions existed get their original version
+	// recorded on the first edit. A concurrent first edit may have recorded
+	// it already.
+	if len(revisions) == 0 {
+		_, err := h.revisionStore.CreateQuestionRevision(ctx.Context(), &types.QuestionRevision{
+			QuestionID: question.ID,
+			Revision: 1,
+			Title: question.Title,
+			Description: question.Description,
+			Tags: question.Tags,
+			TagNames: tagNames(question.TagDetails),
+			EditorID: question.UserID,
+			CreatedAt: question.CreatedAt,
+		})
+		if err != nil && !errors.Is(err, db.ErrConflict) {
+			return err
 		}
 	}
 
-	if question.UserID != user.ID {
-		return ErrUnauthorized()
+	tags, err := h.resolveTags(ctx, user, params.Tags)
+	if err != nil {
+		return err
 	}
 
-	if question.UserID == user.ID {
-		if err := h.questionStore.DeleteQuestionByID(ctx.Context(), id); err != nil {
-			return ErrBadRequest()
-		
+	// The next revision number comes from the question that was read, so
+	// of two concurrent edits the unique (questionID, revision) index and
+	// the conditional EditQuestion let only one through.
+	revision, err := h.revisionStore.CreateQuestionRevision(ctx.Context(), &types.QuestionRevision{
+		QuestionID: question.ID,
+		Revision: types.NextRevision(question.Revision),
+		Title: params.Title,
+		Description: params.Description,
+		Tags: tags,
+		TagNames: params.Tags,
+		EditorID: user.ID,
+		EditSummary: params.EditSummary,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrConflict) {
+			return ErrEditConflict()
 		}
+		return err
+	}
+
+	if err := h.questionStore.EditQuestion(ctx.Context(), revision); err != nil {
+		if errors.Is(err, db.ErrConflict) {
+			return ErrEditConflict()
+		}
+		return err
+	}
+
+	if err := h.syncTagQuestions(ctx, question, tags); err != nil {
+		return err
+	}
+
+	question, err = h.questionStore.GetQuestionByID(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(question)
+}
+
+func (h *QuestionHandler) HandleGetQuestionRevisions(ctx *fiber.Ctx) error {
+	var (
+		id = ctx.Params("id")
+	)
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidID()
+	}
+
+	revisions, err := h.revisionStore.GetQuestionRevisions(ctx.Context(), oid)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(revisions)
+}
+
+func (h *QuestionHandler) HandleGetQuestionRevisionDiff(ctx *fiber.Ctx) error {
+	var (
+		id = ctx.Params("id")
+		from = ctx.QueryInt("from")
+		to = ctx.QueryInt("to")
+	)
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidID()
+	}
+
+	if from <= 0 || to <= 0 {
+		return NewError(fiber.StatusBadRequest, "from and to revisions are required")
+	}
+
+	fromRevision, err := h.revisionStore.GetQuestionRevision(ctx.Context(), oid, from)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(fmt.Sprintf("revision %d", from))
+		}
+		return err
+	}
+
+	toRevision, err := h.revisionStore.GetQuestionRevision(ctx.Context(), oid, to)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(fmt.Sprintf("revision %d", to))
+		}
+		return err
+	}
+
+	return ctx.JSON(types.NewQuestionRevisionDiff(fromRevision, toRevision))
+}
+
+func (h *QuestionHandler) HandleDeleteQuestionByID(ctx *fiber.Ctx) error {
+	var (
+		id = ctx.Params("_id")
+	)
+
+	user, err := getAuthUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	question, err := h.questionStore.GetQuestionByID(ctx.Context(), id)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(id)
+		}
+		return err
+	}
+
+	if !types.Authorize(user, types.ActionDeletePost, question.UserID) {
+		return ErrForbidden()
+	}
+
+	if err := h.questionStore.DeleteQuestion(ctx.Context(), question); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(id)
+		}
+		return err
 	}
 
 	return nil
 }
 
+// HandleQuestionVote sets the caller's vote on the question to up, down or
+// none and returns it with the new score.
 func (h *QuestionHandler) HandleQuestionVote(ctx *fiber.Ctx) error {
-	var params types.QuestionVoteParams
+	var (
+		id = ctx.Params("id")
+		params types.VoteParams
+	)
 
 	if err := ctx.BodyParser(&params); err != nil {
 		return ErrBadRequest()
 	}
 
-	question, err := h.questionStore.GetQuestionByID(ctx.Context(), params.QuestionID)
+	if errors := params.Validate(); len(errors) > 0 {
+		return ErrValidation(errors)
+	}
+
+	user, err := getAuthUser(ctx)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return ErrResourceNotFound(params.QuestionID)
-		}
+		return err
 	}
 
-	user, err := h.userStore.GetUserByID(ctx.Context(), params.UserID)
+	question, err := h.questionStore.GetQuestionByID(ctx.Context(), id)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return ErrResourceNotFound(params.UserID)
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(id)
 		}
+		return err
 	}
 
-	if question.UserID == user.ID {
-		return ErrUnauthorized()
+	if !types.Authorize(user, types.ActionVotePost, question.UserID) {
+		return ErrForbidden()
 	}
 
-	if !params.HasUpvoted && !params.HasDownvoted {
-		return ErrBadRequest()
+	next := *params.Vote
+	if priv, ok := types.VotePrivilege(next); ok {
+		if err := requirePrivilege(user, priv); err != nil {
+			return err
+		}
 	}
 
-	if params.HasUpvoted {
-		if err := h.questionStore.UpvoteQuestion(ctx.Context(), &params); err != nil {
-			return ErrBadRequest()
+	prev, err := h.voteStore.GetVote(ctx.Context(), user.ID, question.ID)
+	if err != nil {
+		return err
+	}
+
+	counted := next != types.VoteNone && next != prev
+	if counted {
+		if err := consumeQuota(ctx.Context(), h.quotaStore, user, types.QuotaVotes); err != nil {
+			return err
 		}
 	}
 
-	if params.HasDownvoted {
-		if err := h.questionStore.DownvoteQuestion(ctx.Context(), &params); err != nil {
-			return ErrBadRequest()
+	vote := &types.Vote{
+		UserID: user.ID,
+		TargetID: question.ID,
+		TargetType: types.VoteOnQuestion,
+		QuestionID: question.ID,
+		OwnerID: question.UserID,
+		Vote: next,
+	}
+
+	result, err := h.voteStore.CastVote(ctx.Context(), vote, prev)
+	if err != nil {
+		if counted {
+			if err := refundQuota(ctx.Context(), h.quotaStore, user, types.QuotaVotes); err != nil {
+				return err
+			}
+		}
+		return err
+	}
+
+	return ctx.JSON(result)
+}
+
+// resolveTags returns the IDs of the named tags, creating the missing ones
+// when the user may create tags.
+func (h *QuestionHandler) resolveTags(ctx *fiber.Ctx, user *types.User, names []string) ([]primitive.ObjectID, error) {
+	tags := make([]primitive.ObjectID, len(names))
+	for i, tagName := range names {
+		tag, err := h.tagStore.GetTagByName(ctx.Context(), tagName)
+		if err != nil {
+			if !errors.Is(err, db.ErrNotFound) {
+				return nil, err
+			}
+
+			if err := requirePrivilege(user, types.PrivCreateTag); err != nil {
+				return nil, err
+			}
+
+			tag, err = h.tagStore.CreateTag(ctx.Context(), &types.Tag{
+				Name: tagName,
+				Followers: []primitive.ObjectID{},
+				CreatedAt: time.Now().UTC(),
+			})
+			// Someone else created it in the meantime.
+			if errors.Is(err, db.ErrTagExists) {
+				tag, err = h.tagStore.GetTagByName(ctx.Context(), tagName)
+			}
+			if err != nil {
+				return nil, err
+			}
 		}
+		tags[i] = tag.ID
 	}
 
-	question, err = h.questionStore.GetQuestionByID(ctx.Context(), params.QuestionID)
+	return tags, nil
+}
+
+func (h *QuestionHandler) nearDuplicates(ctx *fiber.Ctx, draft *types.QuestionDraft) ([]*types.SimilarQuestion, error) {
+	similar, err := h.searchStore.SimilarQuestions(ctx.Context(), draft, similarQuestionLimit)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return ErrResourceNotFound(params.QuestionID)
+		return nil, err
+	}
+
+	duplicates := []*types.SimilarQuestion{}
+	for _, question := range similar {
+		if question.NearDuplicate {
+			duplicates = append(duplicates, question)
 		}
 	}
 
-	return ctx.JSON(question)
-}
\ No newline at end of file
+	return duplicates, nil
+}
+
+// syncTagQuestions recounts the questions of the tags an edit added or
+// removed, and has the author follow the tags that were added. It runs
+// after the edit is stored.
+func (h *QuestionHandler) syncTagQuestions(ctx *fiber.Ctx, question *types.Question, tags []primitive.ObjectID) error {
+	current := make(map[primitive.ObjectID]bool, len(question.Tags))
+	for _, tagID := range question.Tags {
+		current[tagID] = true
+	}
+
+	var (
+		updated = make(map[primitive.ObjectID]bool, len(tags))
+		changed = []primitive.ObjectID{}
+	)
+	for _, tagID := range tags {
+		updated[tagID] = true
+		if current[tagID] {
+			continue
+		}
+
+		update := &types.UpdateTagFollowers{Followers: question.UserID}
+		if err := h.tagStore.UpdateTag(ctx.Context(), db.Map{"_id": tagID}, update); err != nil {
+			return err
+		}
+		changed = append(changed, tagID)
+	}
+
+	for _, tagID := range question.Tags {
+		if !updated[tagID] {
+			changed = append(changed, tagID)
+		}
+	}
+
+	return h.tagStore.CountTagQuestions(ctx.Context(), changed)
+}
+
+func tagNames(tags []*types.Tag) []string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	return names
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"context"
+	"log"
+
+	"github.com/fullstack/dev-overflow/db"
+	"github.com/fullstack/dev-overflow/types"
+	"github.com/fullstack/dev-overflow/utils"
+)
+
+// renderQuestions fills in the sanitized HTML of each question, rendering
+// and caching it when the cached copy is missing or stale. A failed cache
+// write only costs a render on the next read.
+func renderQuestions(ctx context.Context, store db.QuestionStore, questions ...*types.Question) {
+	for _, question := range questions {
+		nofollow := types.LinksNeedNoFollow(question.User)
+		if !question.Rendered.IsCurrent(question.Revision, nofollow) {
+			question.Rendered = &types.RenderedBody{
+				Revision: question.Revision,
+				NoFollow: nofollow,
+				HTML: utils.RenderMarkdown(question.Description, nofollow),
+			}
+			if err := store.SetRendered(ctx, question, question.Rendered); err != nil {
+				log.Println(err)
+			}
+		}
+		question.DescriptionHTML = question.Rendered.HTML
+	}
+}
+
+func renderAnswers(ctx context.Context, store db.AnswerStore, answers ...*types.Answer) {
+	for _, answer := range answers {
+		nofollow := types.LinksNeedNoFollow(answer.User)
+		if !answer.Rendered.IsCurrent(answer.Revision, nofollow) {
+			answer.Rendered = &types.RenderedBody{
+				Revision: answer.Revision,
+				NoFollow: nofollow,
+				HTML: utils.RenderMarkdown(answer.Description, nofollow),
+			}
+			if err := store.SetRendered(ctx, answer, answer.Rendered); err != nil {
+				log.Println(err)
+			}
+		}
+		answer.DescriptionHTML = answer.Rendered.HTML
+	}
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/fullstack/dev-overflow/db"
+	"github.com/gofiber/fiber/v2"
+)
+
+type ReputationHandler struct {
+	reputationStore db.ReputationStore
+	userStore db.UserStore
+}
+
+func NewReputationHandler(reputationStore db.ReputationStore, userStore db.UserStore) *ReputationHandler {
+	return &ReputationHandler{
+		reputationStore: reputationStore,
+		userStore: userStore,
+	}
+}
+
+func (h *ReputationHandler) HandleGetReputationHistory(ctx *fiber.Ctx) error {
+	var (
+		clerkID = ctx.Params("clerkID")
+		params db.PageParams
+	)
+
+	if err := ctx.QueryParser(&params); err != nil {
+		return ErrBadRequest()
+	}
+
+	user, err := h.userStore.GetUserByID(ctx.Context(), clerkID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(clerkID)
+		}
+		return err
+	}
+
+	history, err := h.reputationStore.GetReputationHistory(ctx.Context(), user.ID, &params)
+	if err != nil {
+		return err
+	}
+	history.Reputation = user.Reputation
+
+	return ctx.JSON(history)
+}
@@ -0,0 +1,25 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// parseStrictBody decodes a JSON body like BodyParser but rejects fields the
+// target struct doesn't declare.
+func parseStrictBody(ctx *fiber.Ctx, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(ctx.Body()))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		if field, found := strings.CutPrefix(err.Error(), "json: unknown field "); found {
+			return NewError(fiber.StatusBadRequest, "Unknown field "+field).WithCode(CodeInvalidRequest)
+		}
+		return ErrBadRequest()
+	}
+
+	return nil
+}
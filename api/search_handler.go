@@ -0,0 +1,36 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/fullstack/dev-overflow/db"
+	"github.com/gofiber/fiber/v2"
+)
+
+type SearchHandler struct {
+	searchStore db.SearchStore
+}
+
+func NewSearchHandler(searchStore db.SearchStore) *SearchHandler {
+	return &SearchHandler{
+		searchStore: searchStore,
+	}
+}
+
+func (h *SearchHandler) HandleSearch(ctx *fiber.Ctx) error {
+	var params db.SearchParams
+
+	if err := ctx.QueryParser(&params); err != nil {
+		return ErrBadRequest()
+	}
+
+	results, err := h.searchStore.Search(ctx.Context(), &params)
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidSearchType) {
+			return NewError(fiber.StatusBadRequest, "Invalid search type "+params.Type)
+		}
+		return err
+	}
+
+	return ctx.JSON(results)
+}
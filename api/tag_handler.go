@@ -7,7 +7,7 @@ import (
 	"github.com/fullstack/dev-overflow/db"
 	"github.com/fullstack/dev-overflow/types"
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type TagHandler struct {
@@ -29,9 +29,10 @@ func (h *TagHandler) HandleGetTagByID(ctx *fiber.Ctx) error {
 
 	tag, err := h.tagStore.GetTagByID(ctx.Context(), id)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments){
+		if errors.Is(err, db.ErrNotFound){
 			return ErrResourceNotFound(id)
 		}
+		return err
 	}
 	return ctx.JSON(tag)
 
@@ -44,9 +45,10 @@ func (h *TagHandler) HandleGetTagByName(ctx *fiber.Ctx) error {
 
 	tag, err := h.tagStore.GetTagByName(ctx.Context(), name)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments){
+		if errors.Is(err, db.ErrNotFound){
 			return ErrResourceNotFound(name)
 		}
+		return err
 	}
 	return ctx.JSON(tag)
 }
@@ -54,7 +56,7 @@ func (h *TagHandler) HandleGetTagByName(ctx *fiber.Ctx) error {
 func (h *TagHandler) HandleGetTags(ctx *fiber.Ctx) error {
 	tags, err := h.tagStore.GetTags(ctx.Context())
 	if err != nil {
-		return ErrBadRequest()
+		return err
 	}
 
 	return ctx.JSON(tags)
@@ -64,6 +66,19 @@ func (h *TagHandler) HandleCreateTag(ctx *fiber.Ctx) error {
 	var params types.CreateTagParams
 
 	if err := ctx.BodyParser(&params); err != nil {
+		return ErrBadRequest()
+	}
+
+	if errors := params.Validate(); len(errors) > 0 {
+		return ErrValidation(errors)
+	}
+
+	user, err := getAuthUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := requirePrivilege(user, types.PrivCreateTag); err != nil {
 		return err
 	}
 
@@ -72,26 +87,36 @@ func (h *TagHandler) HandleCreateTag(ctx *fiber.Ctx) error {
 		CreatedAt: time.Now().UTC(),
 	}
 
-	tag, err := h.tagStore.CreateTag(ctx.Context(), tag)
+	tag, err = h.tagStore.CreateTag(ctx.Context(), tag)
 	if err != nil {
-		return ErrBadRequest()
+		return err
 	}
 
 	return ctx.JSON(tag)
 }
 
+// HandleUpdateTag makes the caller follow the tag.
 func (h *TagHandler) HandleUpdateTag(ctx *fiber.Ctx) error {
 	var (
-		params *types.UpdateTagQuestionAndFollowers
-		oid = ctx.Params("_id")
+		id = ctx.Params("_id")
 	)
-	if err := ctx.BodyParser(&params); err != nil {
-		return ErrBadRequest()
+
+	user, err := getAuthUser(ctx)
+	if err != nil {
+		return err
 	}
 
-	filter := db.Map{"_id": oid}
-	if err := h.tagStore.UpdateTag(ctx.Context(), filter, params); err != nil {
-		return ErrBadRequest()
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidID()
+	}
+
+	update := &types.UpdateTagFollowers{Followers: user.ID}
+	if err := h.tagStore.UpdateTag(ctx.Context(), db.Map{"_id": oid}, update); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(id)
+		}
+		return err
 	}
 
 	return ctx.JSON(fiber.Map{"message": "Tag updated successfully"})
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"errors"
 	"os"
 	"time"
@@ -10,20 +11,23 @@ import (
 	"github.com/fullstack/dev-overflow/types"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type UserHandler struct {
 	userStore db.UserStore
 	tagStore db.TagStore
 	questionStore db.QuestionStore
+	answerStore db.AnswerStore
+	voteStore db.VoteStore
 }
 
-func NewUserHandler(userStore db.UserStore, tagStore db.TagStore, questionStore db.QuestionStore) *UserHandler {
+func NewUserHandler(userStore db.UserStore, tagStore db.TagStore, questionStore db.QuestionStore, answerStore db.AnswerStore, voteStore db.VoteStore) *UserHandler {
 	return &UserHandler{
 		userStore: userStore,
 		tagStore: tagStore,
 		questionStore: questionStore,
+		answerStore: answerStore,
+		voteStore: voteStore,
 	}
 }
 
@@ -33,7 +37,7 @@ func (h *UserHandler) HandleSignUp(c *fiber.Ctx) error {
 
 	clerkClient, err := clerk.NewClient(apiKey)
 	if err != nil {
-		return ErrBadRequest()
+		return err
 	}
 
 	session, err := clerkClient.Sessions().Verify(sessionToken, "")
@@ -56,7 +60,7 @@ func (h *UserHandler) HandleSignUp(c *fiber.Ctx) error {
 
 	insertedUser, err := h.userStore.CreateUser(c.Context(), user)
 	if err != nil {
-		return ErrBadRequest()
+		return err
 	}
 
 	return c.JSON(fiber.Map{"message": "User berhasil ditambahkan, ID nya adalah =>" + insertedUser.ClerkID})
@@ -69,12 +73,27 @@ func (h *UserHandler) HandleGetUserByID(ctx *fiber.Ctx) error {
 
 	user, err := h.userStore.GetUserByID(ctx.Context(), id)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
+		if errors.Is(err, db.ErrNotFound) {
 			return ErrResourceNotFound(id)
 		}
+		return err
+	}
+
+	viewer := getOptionalUser(ctx)
+	if viewer != nil && (viewer.ID == user.ID || viewer.Can(types.PermViewPrivateFields)) {
+		return ctx.JSON(user.Private())
 	}
 
-	return ctx.JSON(user)
+	return ctx.JSON(user.Public())
+}
+
+func (h *UserHandler) HandleGetMe(ctx *fiber.Ctx) error {
+	user, err := getAuthUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(user.Private())
 }
 
 func (h *UserHandler) HandleGetUsers(ctx *fiber.Ctx) error {
@@ -87,15 +106,20 @@ func (h *UserHandler) HandleGetUsers(ctx *fiber.Ctx) error {
 
 	users, err := h.userStore.GetUsers(ctx.Context(), params)
 	if err != nil {
-		return ErrBadRequest()
+		return err
+	}
+
+	publicUsers := make([]*types.PublicUser, len(users))
+	for i, user := range users {
+		publicUsers[i] = user.Public()
 	}
 
-	return ctx.JSON(users)
+	return ctx.JSON(publicUsers)
 }
 
-func (h *UserHandler) HandleCreateUser(c *fiber.Ctx) error {
+func (h *UserHandler) HandleSaveQuestion(c *fiber.Ctx) error {
 	var (
-		params types.CreateUserParam
+		params types.SaveQuestionParam
 	)
 
 	if err := c.BodyParser(&params); err != nil {
@@ -103,35 +127,18 @@ func (h *UserHandler) HandleCreateUser(c *fiber.Ctx) error {
 	}
 
 	if errors := params.Validate(); len(errors) > 0 {
-		return c.JSON(errors)
-	
+		return ErrValidation(errors)
 	}
 
-	user, err := types.NewUserFromParams(params)
+	user, err := getAuthUser(c)
 	if err != nil {
-		return ErrBadRequest()
-	}
-
-	insertedUser, err := h.userStore.CreateUser(c.Context(), user)
-	if err != nil {
-		return ErrBadRequest()
-	}
-
-	return c.JSON(insertedUser)
-}
-
-func (h *UserHandler) HandleSaveQuestion(c *fiber.Ctx) error {
-	var (
-		params types.SaveQuestionParam
-	)
-
-	if err := c.BodyParser(&params); err != nil {
-		return ErrBadRequest()
+		return err
 	}
+	params.UserID = user.ClerkID
 
 	saved, err := h.userStore.SaveQuestion(c.Context(), &params)
 	if err != nil {
-		return ErrBadRequest()
+		return err
 	}
 
 	if saved {
@@ -144,24 +151,32 @@ func (h *UserHandler) HandleSaveQuestion(c *fiber.Ctx) error {
 func (h *UserHandler) HandleUpdateUser(c *fiber.Ctx) error {
 	var (
 		clerkID = c.Params("clerkID")
-		params *types.UpdateUserParam
+		params types.UpdateUserParam
 	)
 
-	if err := c.BodyParser(&params); err != nil {
-		return ErrBadRequest()
+	if err := parseStrictBody(c, &params); err != nil {
+		return err
 	}
 
-	if params.UpdateData["email"] != nil || params.UpdateData["firstName"] != nil || params.UpdateData["lastName"] != nil {
-		if errors := params.Validate(); len(errors) > 0 {
-			return c.JSON(errors)
-		}
+	user, err := getAuthUser(c)
+	if err != nil {
+		return err
 	}
 
-	if err := h.userStore.UpdateUser(c.Context(), clerkID, params); err != nil {
-		return ErrBadRequest()
+	if user.ClerkID != clerkID {
+		return ErrForbidden()
+	}
+
+	if errors := params.Validate(); len(errors) > 0 {
+		return ErrValidation(errors)
+	}
+
+	updatedUser, err := h.userStore.UpdateUser(c.Context(), clerkID, params)
+	if err != nil {
+		return err
 	}
 
-	return c.JSON(map[string]string{"message": "User berhasil diupdate dengan ID => " + clerkID})
+	return c.JSON(updatedUser.Private())
 }
 
 func (h *UserHandler) HandleDeleteUser(c *fiber.Ctx) error {
@@ -169,37 +184,45 @@ func (h *UserHandler) HandleDeleteUser(c *fiber.Ctx) error {
 		clerkID = c.Params("clerkID")
 	)
 
-	user, err := h.userStore.GetUserByID(c.Context(), clerkID)
+	user, err := getAuthUser(c)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return ErrResourceNotFound(clerkID)
-		}
+		return err
 	}
 
-	if err := h.tagStore.UpdateManyFollowersByID(c.Context(), user.ID); err != nil {
-		return ErrBadRequest()
+	if user.ClerkID != clerkID {
+		return ErrForbidden()
 	}
 
-	questions, err := h.questionStore.GetQuestionsByUserID(c.Context(), clerkID)
-	if err != nil {
-		return ErrBadRequest()
+	if err := removeUser(c.Context(), h.userStore, h.tagStore, h.questionStore, h.answerStore, h.voteStore, user); err != nil {
+		return err
 	}
 
-	for i := 0; i < len(questions); i++ {
-		if err := h.tagStore.UpdateManyQuestionsByID(c.Context(), questions[i].ID); err != nil {
-			return ErrBadRequest()
-		}
+	return c.JSON(map[string]string{"message": "User berhasil dihapus dengan ID => " + clerkID})
+}
+
+// removeUser deletes the user together with their votes, answers and
+// questions, and everything hanging off those, and takes them out of the
+// tag followers lists. Every post and vote is removed in its own
+// transaction and the user goes last, so a removal cut short is finished by
+// retrying it.
+func removeUser(ctx context.Context, userStore db.UserStore, tagStore db.TagStore, questionStore db.QuestionStore, answerStore db.AnswerStore, voteStore db.VoteStore, user *types.User) error {
+	if err := voteStore.DeleteVotesByUserID(ctx, user.ID); err != nil {
+		return err
 	}
 
-	if err := h.questionStore.DeleteManyQuestionsByUserID(c.Context(), user.ID); err != nil {
-		return ErrBadRequest()
+	if err := answerStore.DeleteManyAnswersByUserID(ctx, user.ID); err != nil {
+		return err
 	}
 
-	if err := h.userStore.DeleteUser(c.Context(), clerkID); err != nil {
-		return ErrBadRequest()
+	if err := questionStore.DeleteManyQuestionsByUserID(ctx, user.ID); err != nil {
+		return err
 	}
 
-	return c.JSON(map[string]string{"message": "User berhasil dihapus dengan ID => " + clerkID})
+	if err := tagStore.UpdateManyFollowersByID(ctx, user.ID); err != nil {
+		return err
+	}
+
+	return userStore.DeleteUser(ctx, user.ClerkID)
 }
 
 func (h *UserHandler) HandleSayHello(c *fiber.Ctx) error {
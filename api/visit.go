@@ -0,0 +1,39 @@
+package api
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/fullstack/dev-overflow/db"
+	"github.com/gofiber/fiber/v2"
+)
+
+// TrackVisits records a visit for the authenticated user once per UTC day
+// for the visit streak badges. The days already recorded are remembered so
+// only the first request of a day writes. It must run after
+// JWTAuthentication.
+func TrackVisits(interactionStore db.InteractionStore) fiber.Handler {
+	var visited sync.Map
+
+	return func(ctx *fiber.Ctx) error {
+		user := getOptionalUser(ctx)
+		if user == nil {
+			return ctx.Next()
+		}
+
+		now := time.Now().UTC()
+		day := now.Format(time.DateOnly)
+		if last, ok := visited.Load(user.ID); ok && last.(string) == day {
+			return ctx.Next()
+		}
+
+		if err := interactionStore.RecordVisit(ctx.Context(), user.ID, now); err != nil {
+			log.Printf("record visit of %s: %v", user.ID.Hex(), err)
+		} else {
+			visited.Store(user.ID, day)
+		}
+
+		return ctx.Next()
+	}
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"context"
+
+	"github.com/fullstack/dev-overflow/db"
+	"github.com/fullstack/dev-overflow/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// setQuestionVotes fills in the viewer's vote on each question with a single
+// votes query. Anonymous viewers have none and get no myVote field.
+func setQuestionVotes(ctx context.Context, store db.VoteStore, viewer *types.User, questions ...*types.Question) error {
+	if viewer == nil || len(questions) == 0 {
+		return nil
+	}
+
+	ids := make([]primitive.ObjectID, len(questions))
+	for i, question := range questions {
+		ids[i] = question.ID
+	}
+
+	votes, err := store.GetVotes(ctx, viewer.ID, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, question := range questions {
+		vote := votes[question.ID]
+		question.MyVote = &vote
+	}
+
+	return nil
+}
+
+func setAnswerVotes(ctx context.Context, store db.VoteStore, viewer *types.User, answers ...*types.Answer) error {
+	if viewer == nil || len(answers) == 0 {
+		return nil
+	}
+
+	ids := make([]primitive.ObjectID, len(answers))
+	for i, answer := range answers {
+		ids[i] = answer.ID
+	}
+
+	votes, err := store.GetVotes(ctx, viewer.ID, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, answer := range answers {
+		vote := votes[answer.ID]
+		answer.MyVote = &vote
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/fullstack/dev-overflow/db"
+	"github.com/fullstack/dev-overflow/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+// VoteAuditHandler lets moderators review the votes reversed by the fraud
+// detection.
+type VoteAuditHandler struct {
+	auditStore db.VoteAuditStore
+}
+
+func NewVoteAuditHandler(auditStore db.VoteAuditStore) *VoteAuditHandler {
+	return &VoteAuditHandler{
+		auditStore: auditStore,
+	}
+}
+
+func (h *VoteAuditHandler) HandleGetVoteAudits(ctx *fiber.Ctx) error {
+	var params db.VoteAuditQueryParams
+
+	if err := ctx.QueryParser(&params); err != nil {
+		return ErrBadRequest()
+	}
+
+	audits, err := h.auditStore.GetAudits(ctx.Context(), &params)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(audits)
+}
+
+func (h *VoteAuditHandler) HandleGetVoteAudit(ctx *fiber.Ctx) error {
+	var (
+		id = ctx.Params("id")
+	)
+
+	audit, err := h.auditStore.GetAuditByID(ctx.Context(), id)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(id)
+		}
+		return err
+	}
+
+	return ctx.JSON(audit)
+}
+
+// HandleReviewVoteAudit confirms or overrides an audit. Overriding casts the
+// reversed votes again with their reputation and exempts them from future
+// detection, except votes their voter has recast since. Restoring an audit
+// whose restore was cut short resumes it.
+func (h *VoteAuditHandler) HandleReviewVoteAudit(ctx *fiber.Ctx) error {
+	var (
+		id = ctx.Params("id")
+		params types.ReviewVoteAuditParams
+	)
+
+	if err := ctx.BodyParser(&params); err != nil {
+		return ErrBadRequest()
+	}
+
+	if errors := params.Validate(); len(errors) > 0 {
+		return ErrValidation(errors)
+	}
+
+	user, err := getAuthUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	audit, err := h.auditStore.GetAuditByID(ctx.Context(), id)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrResourceNotFound(id)
+		}
+		return err
+	}
+
+	if audit.Status != types.AuditRestoring || params.Decision != types.DecisionRestore {
+		audit, err = h.auditStore.ReviewAudit(ctx.Context(), audit.ID, params.Decision.Status(), user.ID, params.Note)
+		if err != nil {
+			return err
+		}
+	}
+
+	if params.Decision != types.DecisionRestore {
+		return ctx.JSON(audit)
+	}
+
+	audit, err = h.auditStore.RestoreVotes(ctx.Context(), audit)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(audit)
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/clerkinc/clerk-sdk-go/clerk"
+	"github.com/fullstack/dev-overflow/db"
+	"github.com/fullstack/dev-overflow/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	clerkUserCreated = "user.created"
+	clerkUserUpdated = "user.updated"
+	clerkUserDeleted = "user.deleted"
+)
+
+type clerkWebhookEvent struct {
+	Type string `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+type WebhookHandler struct {
+	verifier *SvixVerifier
+	userStore db.UserStore
+	tagStore db.TagStore
+	questionStore db.QuestionStore
+	answerStore db.AnswerStore
+	voteStore db.VoteStore
+}
+
+func NewWebhookHandler(verifier *SvixVerifier, userStore db.UserStore, tagStore db.TagStore, questionStore db.QuestionStore, answerStore db.AnswerStore, voteStore db.VoteStore) *WebhookHandler {
+	return &WebhookHandler{
+		verifier: verifier,
+		userStore: userStore,
+		tagStore: tagStore,
+		questionStore: questionStore,
+		answerStore: answerStore,
+		voteStore: voteStore,
+	}
+}
+
+// HandleClerkWebhook keeps the users collection in sync with Clerk. Svix
+// retries deliveries, so every event is applied idempotently.
+func (h *WebhookHandler) HandleClerkWebhook(ctx *fiber.Ctx) error {
+	payload := ctx.Body()
+
+	if err := h.verifier.Verify(ctx.Get("svix-id"), ctx.Get("svix-timestamp"), ctx.Get("svix-signature"), payload); err != nil {
+		return ErrUnauthorized()
+	}
+
+	var event clerkWebhookEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return ErrBadRequest()
+	}
+
+	var clerkUser clerk.User
+	if err := json.Unmarshal(event.Data, &clerkUser); err != nil || clerkUser.ID == "" {
+		return ErrBadRequest()
+	}
+
+	switch event.Type {
+	case clerkUserCreated, clerkUserUpdated:
+		if err := h.syncUser(ctx, &clerkUser); err != nil {
+			return err
+		}
+	case clerkUserDeleted:
+		if err := h.deleteUser(ctx, clerkUser.ID); err != nil {
+			return err
+		}
+	default:
+		log.Printf("ignoring clerk webhook event %s", event.Type)
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Webhook received", "type": event.Type})
+}
+
+// syncUser creates the user on first sight and updates it afterwards, which
+// makes replayed and out of order created/updated events harmless. Losing a
+// create race against a concurrent delivery hits the unique clerkID index
+// and falls back to the update.
+func (h *WebhookHandler) syncUser(ctx *fiber.Ctx, clerkUser *clerk.User) error {
+	params := createUserParamFromClerk(clerkUser)
+
+	_, err := h.userStore.GetUserByID(ctx.Context(), clerkUser.ID)
+	if err != nil {
+		if !errors.Is(err, db.ErrNotFound) {
+			return err
+		}
+
+		user, err := types.NewUserFromParams(params)
+		if err != nil {
+			return err
+		}
+
+		_, err = h.userStore.CreateUser(ctx.Context(), user)
+		if !errors.Is(err, db.ErrConflict) {
+			return err
+		}
+	}
+
+	update := types.AdminUpdateUserParam{
+		UpdateUserParam: types.UpdateUserParam{
+			FirstName: &params.FirstName,
+			LastName: &params.LastName,
+			Picture: &params.Picture,
+		},
+		Email: &params.Email,
+	}
+
+	_, err = h.userStore.UpdateUser(ctx.Context(), clerkUser.ID, update)
+	return err
+}
+
+func (h *WebhookHandler) deleteUser(ctx *fiber.Ctx, clerkID string) error {
+	user, err := h.userStore.GetUserByID(ctx.Context(), clerkID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	return removeUser(ctx.Context(), h.userStore, h.tagStore, h.questionStore, h.answerStore, h.voteStore, user)
+}
+
+func createUserParamFromClerk(clerkUser *clerk.User) types.CreateUserParam {
+	params := types.CreateUserParam{
+		ClerkID: clerkUser.ID,
+		Picture: clerkUser.ProfileImageURL,
+	}
+
+	if clerkUser.FirstName != nil {
+		params.FirstName = *clerkUser.FirstName
+	}
+
+	if clerkUser.LastName != nil {
+		params.LastName = *clerkUser.LastName
+	}
+
+	if clerkUser.ImageURL != nil {
+		params.Picture = *clerkUser.ImageURL
+	}
+
+	for _, email := range clerkUser.EmailAddresses {
+		if clerkUser.PrimaryEmailAddressID != nil && email.ID == *clerkUser.PrimaryEmailAddressID {
+			params.Email = email.EmailAddress
+			break
+		}
+	}
+
+	return params
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fullstack/dev-overflow/db"
+	"github.com/fullstack/dev-overflow/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+const testWebhookSecret = svixSecretPrefix + "c2VjcmV0LWtleS1mb3ItdGVzdHM="
+
+const userCreatedFixture = `{
+	"type": "user.created",
+	"data": {
+		"id": "user_1",
+		"first_name": "Ada",
+		"last_name": "Lovelace",
+		"image_url": "https://img.clerk.com/ada.png",
+		"primary_email_address_id": "idn_1",
+		"email_addresses": [
+			{"id": "idn_0", "email_address": "old@example.com"},
+			{"id": "idn_1", "email_address": "ada@example.com"}
+		]
+	}
+}`
+
+const userDeletedFixture = `{"type": "user.deleted", "data": {"id": "user_404", "deleted": true}}`
+
+// CreateUser mimics the unique clerkID index.
+func (s *fakeUserStore) CreateUser(_ context.Context, user *types.User) (*types.User, error) {
+	if _, ok := s.users[user.ClerkID]; ok {
+		return nil, db.ErrConflict
+	}
+	s.users[user.ClerkID] = user
+	return user, nil
+}
+
+func (s *fakeUserStore) UpdateUser(_ context.Context, clerkID string, update types.UserUpdate) (*types.User, error) {
+	user, ok := s.users[clerkID]
+	if !ok {
+		return nil, db.ErrNotFound
+	}
+
+	fields := update.Fields()
+	if email, ok := fields["email"].(string); ok {
+		user.Email = email
+	}
+	if firstName, ok := fields["firstName"].(string); ok {
+		user.FirstName = firstName
+	}
+	return user, nil
+}
+
+// racingUserStore misses the user on lookup as if a concurrent delivery
+// inserted it between the lookup and the insert.
+type racingUserStore struct {
+	*fakeUserStore
+}
+
+func (s racingUserStore) GetUserByID(context.Context, string) (*types.User, error) {
+	return nil, db.ErrNotFound
+}
+
+func newTestWebhookApp(t *testing.T, userStore db.UserStore) (*fiber.App, *SvixVerifier) {
+	t.Helper()
+
+	verifier, err := NewSvixVerifier(testWebhookSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Post("/webhooks/clerk", NewWebhookHandler(verifier, userStore, nil, nil, nil, nil).HandleClerkWebhook)
+	return app, verifier
+}
+
+func postWebhook(t *testing.T, app *fiber.App, id, timestamp, signature, payload string) int {
+	t.Helper()
+
+	req := httptest.NewRequest(fiber.MethodPost, "/webhooks/clerk", strings.NewReader(payload))
+	req.Header.Set("svix-id", id)
+	req.Header.Set("svix-timestamp", timestamp)
+	req.Header.Set("svix-signature", signature)
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode
+}
+
+func TestNewSvixVerifierRejectsEmptySecret(t *testing.T) {
+	for _, secret := range []string{"", svixSecretPrefix} {
+		if _, err := NewSvixVerifier(secret); err == nil {
+			t.Fatalf("expected an error for secret %q", secret)
+		}
+	}
+}
+
+func TestHandleClerkWebhookSignature(t *testing.T) {
+	app, verifier := newTestWebhookApp(t, &fakeUserStore{users: map[string]*types.User{}})
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	forged := base64.StdEncoding.EncodeToString([]byte("forged"))
+
+	tests := []struct {
+		name string
+		timestamp string
+		signature string
+		status int
+	}{
+		{name: "valid", timestamp: now, signature: "v1," + verifier.Sign("msg_1", now, []byte(userDeletedFixture)), status: fiber.StatusOK},
+		{name: "rotated secrets", timestamp: now, signature: "v1," + forged + " v1," + verifier.Sign("msg_1", now, []byte(userDeletedFixture)), status: fiber.StatusOK},
+		{name: "forged", timestamp: now, signature: "v1," + forged, status: fiber.StatusUnauthorized},
+		{name: "stale", timestamp: stale, signature: "v1," + verifier.Sign("msg_1", stale, []byte(userDeletedFixture)), status: fiber.StatusUnauthorized},
+		{name: "missing", timestamp: now, status: fiber.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := postWebhook(t, app, "msg_1", tt.timestamp, tt.signature, userDeletedFixture)
+			if status != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, status)
+			}
+		})
+	}
+}
+
+func TestHandleClerkWebhookUserCreated(t *testing.T) {
+	tests := []struct {
+		name string
+		store func(*fakeUserStore) db.UserStore
+		deliveries int
+	}{
+		{name: "first delivery", store: func(s *fakeUserStore) db.UserStore { return s }, deliveries: 1},
+		{name: "replayed delivery", store: func(s *fakeUserStore) db.UserStore { return s }, deliveries: 3},
+		{name: "lost create race", store: func(s *fakeUserStore) db.UserStore { return racingUserStore{s} }, deliveries: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := &fakeUserStore{users: map[string]*types.User{}}
+			app, verifier := newTestWebhookApp(t, tt.store(users))
+			now := strconv.FormatInt(time.Now().Unix(), 10)
+			signature := "v1," + verifier.Sign("msg_1", now, []byte(userCreatedFixture))
+
+			for i := 0; i < tt.deliveries; i++ {
+				if status := postWebhook(t, app, "msg_1", now, signature, userCreatedFixture); status != fiber.StatusOK {
+					t.Fatalf("delivery %d: expected status 200, got %d", i, status)
+				}
+			}
+
+			if len(users.users) != 1 {
+				t.Fatalf("expected 1 user, got %d", len(users.users))
+			}
+
+			user := users.users["user_1"]
+			if user == nil || user.Email != "ada@example.com" || user.FirstName != "Ada" {
+				t.Fatalf("unexpected user %+v", user)
+			}
+		})
+	}
+}
@@ -2,45 +2,80 @@ package db
 
 import (
 	"context"
+	"errors"
 	"os"
 
 	"github.com/fullstack/dev-overflow/types"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 const ANSWERCOLL = "answers"
 
+// ErrAcceptChanged is returned when the accepted answer of a question
+// changed between reading it and accepting.
+var ErrAcceptChanged = newStoreError(ErrConflict, "accepted answer changed concurrently")
+
 type AnswerStore interface {
+	EnsureIndexes(context.Context) error
 	GetAnswerByID(context.Context, string) (*types.Answer, error)
-	GetAnswersByUserID(context.Context, string) ([]*types.Answer, error)
-	GetAnswersOfQuestion(context.Context, string) ([]*types.Answer, error)
+	GetAnswersByUserID(context.Context, string, *AnswerQueryParams) (*types.AnswerList, error)
+	GetAnswersOfQuestion(context.Context, string, *AnswerQueryParams) (*types.AnswerList, error)
+	GetAllAnswersOfQuestion(context.Context, primitive.ObjectID) ([]*types.Answer, error)
 	CreateAnswer(context.Context, *types.Answer) (*types.Answer,error)
-	UpvoteAnswer(context.Context, *types.VoteAnswerParams) error
-	DownvoteAnswer(context.Context, *types.VoteAnswerParams) error
-	DeleteAnswerByID(context.Context, string) error
+	AcceptAnswer(context.Context, *types.Question, *types.Answer, bool) error
+	DeleteAnswer(context.Context, *types.Answer) error
+	DeleteManyAnswersByUserID(context.Context, primitive.ObjectID) error
+	SetRendered(context.Context, *types.Answer, *types.RenderedBody) error
+	EditAnswer(context.Context, *types.AnswerRevision) error
 }
 
 type MongoAnswerStore struct {
 	client *mongo.Client
+	database *mongo.Database
 	coll *mongo.Collection
 	UserStore
+	ReputationStore
 }
 
-func NewMongoAnswerStore(client *mongo.Client, userStore UserStore) *MongoAnswerStore {
+func NewMongoAnswerStore(client *mongo.Client, userStore UserStore, reputationStore ReputationStore) *MongoAnswerStore {
 	var mongoenvdbname = os.Getenv("MONGO_DB_NAME")
 	return &MongoAnswerStore{
 		client: client,
+		database: client.Database(mongoenvdbname),
 		coll: client.Database(mongoenvdbname).Collection(ANSWERCOLL),
 		UserStore: userStore,
+		ReputationStore: reputationStore,
+	}
+}
+
+// EnsureIndexes creates the indexes a question's answers and a user's
+// answers are listed and counted by.
+func (s *MongoAnswerStore) EnsureIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "questionID", Value: 1}},
+			Options: options.Index().SetName("answer_question"),
+		},
+		{
+			Keys: bson.D{{Key: "userID", Value: 1}},
+			Options: options.Index().SetName("answer_user"),
+		},
+	}
+
+	if _, err := s.coll.Indexes().CreateMany(ctx, indexes); err != nil {
+		return storeError(err)
 	}
+
+	return nil
 }
 
 func (s *MongoAnswerStore) GetAnswerByID(ctx context.Context, id string) (*types.Answer, error) {
 	var answer types.Answer
 
-	oid, err := primitive.ObjectIDFromHex(id)
+	oid, err := objectID(id)
 	if err != nil {
 		return nil, err
 	}
@@ -49,203 +84,386 @@ func (s *MongoAnswerStore) GetAnswerByID(ctx context.Context, id string) (*types
 		{
 			"$match": bson.M{"_id": oid},
 		},
+		lookupPublicUser("userID", "user"),
 		{
-			"$lookup":bson.M{
-				"from": "users",
-				"localField": "userID",
-				"foreignField": "_id",
-				"as": "user",
-			},
+			"$unwind": "$user",
 		},
+		lookupPublicUser("lastEditedBy", "lastEditor"),
 		{
-			"$unwind": "$user",
+			"$unwind": bson.M{"path": "$lastEditor", "preserveNullAndEmptyArrays": true},
 		},
 	}
 
 	cursor, err := s.coll.Aggregate(ctx, pipeline)
 	if err != nil {
-		return nil, err
+		return nil, storeError(err)
 	}
 
 	defer cursor.Close(ctx)
 	if cursor.Next(ctx) {
 		if err := cursor.Decode(&answer); err != nil {
-			return nil, err
+			return nil, storeError(err)
 		}
 	} else {
-		return nil, mongo.ErrNoDocuments
+		return nil, ErrNotFound
 	}
 
 	return &answer, nil
 }
 
-func (s *MongoAnswerStore) GetAnswersByUserID(ctx context.Context, id string) ([]*types.Answer, error) {
+func (s *MongoAnswerStore) GetAnswersByUserID(ctx context.Context, id string, params *AnswerQueryParams) (*types.AnswerList, error) {
 	user, err := s.UserStore.GetUserByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	oid := user.ID
+	questionDetails := []bson.M{
+		{"$lookup": bson.M{
+			"from": QUESTIONCOLL,
+			"localField": "questionID",
+			"foreignField": "_id",
+			"as": "questionDetails",
+		}},
+		{"$unwind": bson.M{"path": "$questionDetails", "preserveNullAndEmptyArrays": true}},
+	}
+
+	return s.listAnswers(ctx, bson.M{"userID": user.ID}, params, false, questionDetails...)
+}
+
+// GetAnswersOfQuestion returns one page of a question's answers with the
+// accepted answer pinned on top.
+func (s *MongoAnswerStore) GetAnswersOfQuestion(ctx context.Context, id string, params *AnswerQueryParams) (*types.AnswerList, error) {
+	oid, err := objectID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.listAnswers(ctx, bson.M{"questionID": oid}, params, true)
+}
+
+// listAnswers returns one page of the answers matching match in the sort
+// order of params. Extra stages run on the page only.
+func (s *MongoAnswerStore) listAnswers(ctx context.Context, match bson.M, params *AnswerQueryParams, pinAccepted bool, extra ...bson.M) (*types.AnswerList, error) {
+	page, limit, skip := pageBounds(params.Page, params.Limit, "")
+
+	var sort bson.D
+	if pinAccepted {
+		sort = append(sort, bson.E{Key: "isAccepted", Value: -1})
+	}
+
+	switch params.Sort {
+	case AnswerSortNewest:
+		sort = append(sort, bson.E{Key: "createdAt", Value: -1})
+	case AnswerSortOldest:
+		sort = append(sort, bson.E{Key: "createdAt", Value: 1})
+	case AnswerSortActive:
+		sort = append(sort, bson.E{Key: "lastActivityAt", Value: -1})
+	default:
+		sort = append(sort, bson.E{Key: "score", Value: -1}, bson.E{Key: "createdAt", Value: 1})
+	}
+	sort = append(sort, bson.E{Key: "_id", Value: 1})
+
+	pageStages := []bson.M{
+		{"$skip": skip},
+		{"$limit": limit},
+		lookupPublicUser("userID", "user"),
+		{"$unwind": "$user"},
+		lookupPublicUser("lastEditedBy", "lastEditor"),
+		{"$unwind": bson.M{"path": "$lastEditor", "preserveNullAndEmptyArrays": true}},
+	}
 
 	pipeline := []bson.M{
-		{
-			"$match": bson.M{"userID": oid},
-		},
-		{
-			"$lookup":bson.M{
-				"from": "users",
-				"localField": "userID",
-				"foreignField": "_id",
-				"as": "user",
-			},
-		},
-		{
-			"lookup": bson.M{
-				"from": "questions",
-				"localField": "questionID",
-				"foreignField": "_id",
-				"as": "questionDetails",
-			},
-		},
-		{
-			"$sort": bson.M{"upvotes": -1},
-		},
+		{"$match": match},
+		{"$addFields": bson.M{
+			"lastActivityAt": bson.M{"$ifNull": []any{"$lastEditedAt", "$createdAt"}},
+		}},
+		{"$sort": sort},
+		{"$facet": bson.M{
+			"metadata": []bson.M{{"$count": "total"}},
+			"answers": append(pageStages, extra...),
+		}},
 	}
 
 	cursor, err := s.coll.Aggregate(ctx, pipeline)
 	if err != nil {
-		return nil, err
+		return nil, storeError(err)
 	}
 
 	defer cursor.Close(ctx)
-	var answers []*types.Answer
 
-	for cursor.Next(ctx) {
-		var answer types.Answer
-		if err := cursor.Decode(&answer); err != nil {
-			return nil, err
-		}
-		answers = append(answers, &answer)
-	
+	var result struct {
+		Metadata []struct {
+			Total int64 `bson:"total"`
+		} `bson:"metadata"`
+		Answers []*types.Answer `bson:"answers"`
 	}
 
-	return answers, nil
-
-}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&result); err != nil {
+			return nil, storeError(err)
+		}
+	}
 
-func (s *MongoAnswerStore) GetAnswersOfQuestion(ctx context.Context, id string) ([]*types.Answer, error) {
-	var answers []*types.Answer
+	list := &types.AnswerList{
+		Answers: result.Answers,
+		Page: page,
+		Limit: limit,
+	}
 
-	oid, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return nil, err
+	if list.Answers == nil {
+		list.Answers = []*types.Answer{}
 	}
 
-	pipeline := []bson.M{
-		{
-			"$match": bson.M{"questionID": oid},
-		},
-		{
-			"$lookup":bson.M{
-				"from": "users",
-				"localField": "userID",
-				"foreignField": "_id",
-				"as": "user",
-			},
-		},
-		{
-			"$unwind": "$user",
-		},
-		{"$sort": bson.M{"createdAt": -1}},
+	if len(result.Metadata) > 0 {
+		list.Total = result.Metadata[0].Total
 	}
 
-	cursor, err := s.coll.Aggregate(ctx, pipeline)
+	list.HasNext = skip+int64(len(list.Answers)) < list.Total
+
+	return list, nil
+}
+
+// GetAllAnswersOfQuestion returns every answer of a question, unpaged and
+// without authors, for cascades.
+func (s *MongoAnswerStore) GetAllAnswersOfQuestion(ctx context.Context, questionID primitive.ObjectID) ([]*types.Answer, error) {
+	answers := []*types.Answer{}
+
+	cursor, err := s.coll.Find(ctx, bson.M{"questionID": questionID})
 	if err != nil {
-		return nil, err
+		return nil, storeError(err)
 	}
 
 	if err := cursor.All(ctx, &answers); err != nil {
-		return nil, err
+		return nil, storeError(err)
 	}
 
 	return answers, nil
 }
 
+// CreateAnswer stores the answer and bumps the answer counts of its
+// question and author in one transaction.
 func (s *MongoAnswerStore) CreateAnswer(ctx context.Context, answer *types.Answer) ( *types.Answer, error) {
-	res, err := s.coll.InsertOne(ctx, answer)
+	_, err := withTransaction(ctx, s.client, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		res, err := s.coll.InsertOne(sessCtx, answer)
+		if err != nil {
+			return nil, storeError(err)
+		}
+
+		answer.ID = res.InsertedID.(primitive.ObjectID)
+
+		questionUpdate := bson.M{"$inc": bson.M{"answerCount": 1}}
+		questionRes, err := s.database.Collection(QUESTIONCOLL).UpdateOne(sessCtx, bson.M{"_id": answer.QuestionID}, questionUpdate)
+		if err != nil {
+			return nil, storeError(err)
+		}
+		if questionRes.MatchedCount == 0 {
+			return nil, ErrNotFound
+		}
+
+		return nil, s.UserStore.IncrementAnswerCount(sessCtx, answer.UserID, 1)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	answer.ID = res.InsertedID.(primitive.ObjectID)
+	return answer, nil
+}
 
-	_ = s.UserStore.UpdateUserAnswersField(ctx, answer.UserID, answer.ID)
+// AcceptAnswer accepts or un-accepts answer on question and moves the
+// accept reputation with it, taking it back from a previously accepted
+// answer, in one transaction. The answer and the question only change while
+// they are still as read, so of two racing accepts one fails with
+// ErrAcceptChanged.
+func (s *MongoAnswerStore) AcceptAnswer(ctx context.Context, question *types.Question, answer *types.Answer, accept bool) error {
+	_, err := withTransaction(ctx, s.client, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		filter := bson.M{"_id": answer.ID, "questionID": question.ID, "isAccepted": answer.IsAccepted}
+		res, err := s.coll.UpdateOne(sessCtx, filter, bson.M{"$set": bson.M{"isAccepted": accept}})
+		if err != nil {
+			return nil, storeError(err)
+		}
+		if res.MatchedCount == 0 {
+			return nil, ErrAcceptChanged
+		}
 
-	return answer, nil
+		others := bson.M{"questionID": question.ID, "_id": bson.M{"$ne": answer.ID}, "isAccepted": true}
+		if _, err := s.coll.UpdateMany(sessCtx, others, bson.M{"$set": bson.M{"isAccepted": false}}); err != nil {
+			return nil, storeError(err)
+		}
+
+		questionFilter := bson.M{"_id": question.ID, "acceptedAnswerID": bson.M{"$exists": false}}
+		if question.AcceptedAnswerID != nil {
+			questionFilter["acceptedAnswerID"] = *question.AcceptedAnswerID
+		}
+		questionUpdate := bson.M{"$unset": bson.M{"acceptedAnswerID": ""}}
+		if accept {
+			questionUpdate = bson.M{"$set": bson.M{"acceptedAnswerID": answer.ID}}
+		}
+		res, err = s.database.Collection(QUESTIONCOLL).UpdateOne(sessCtx, questionFilter, questionUpdate)
+		if err != nil {
+			return nil, storeError(err)
+		}
+		if res.MatchedCount == 0 {
+			return nil, ErrAcceptChanged
+		}
+
+		// Switching to another answer takes the reward back from the
+		// previously accepted one.
+		if question.AcceptedAnswerID != nil && *question.AcceptedAnswerID != answer.ID {
+			previous := types.PostRef{QuestionID: question.ID, AnswerID: question.AcceptedAnswerID}
+			if err := s.recordAccept(sessCtx, previous, question.UserID, false); err != nil {
+				return nil, err
+			}
+		}
+
+		if accept == answer.IsAccepted {
+			return nil, nil
+		}
+
+		answerID := answer.ID
+		post := types.PostRef{QuestionID: question.ID, AnswerID: &answerID, OwnerID: answer.UserID}
+		return nil, s.recordAccept(sessCtx, post, question.UserID, accept)
+	})
+
+	return err
 }
 
-func (s *MongoAnswerStore) UpvoteAnswer(ctx context.Context, params *types.VoteAnswerParams) error {
-	user, err := s.UserStore.GetUserByID(ctx, params.UserID)
-	if err != nil {
-		return err
-	}
-	
-	answer, err := s.GetAnswerByID(ctx, params.AnswerID)
-	if err != nil {
+// recordAccept rewards the answerer and the asker for an accepted answer, or
+// takes the reward back when it is un-accepted. Accepting your own answer is
+// worth nothing.
+func (s *MongoAnswerStore) recordAccept(ctx context.Context, answer types.PostRef, askerID primitive.ObjectID, accept bool) error {
+	if !accept {
+		_, err := s.ReputationStore.ReverseEvents(ctx, &ReputationEventFilter{
+			AnswerID: answer.AnswerID,
+			Reasons: []types.ReputationReason{types.ReasonAnswerAccepted, types.ReasonAcceptedAnswer},
+		})
 		return err
 	}
 
-	filter := bson.M{"_id": answer.ID}
+	if answer.OwnerID == askerID {
+		return nil
+	}
 
-	updateDoc := bson.M{
-		"$pull": bson.M{"downvotes": user.ID},
-		"$addToSet": bson.M{"upvotes": user.ID},
+	events := []*types.ReputationEvent{
+		types.NewReputationEvent(answer.OwnerID, askerID, types.ReasonAnswerAccepted, answer),
+		types.NewReputationEvent(askerID, askerID, types.ReasonAcceptedAnswer, answer),
 	}
 
-	_, err = s.coll.UpdateOne(ctx, filter, updateDoc)
-	if err != nil {
-		return err
+	for _, event := range events {
+		if _, err := s.ReputationStore.RecordEvent(ctx, event); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func (s *MongoAnswerStore) DownvoteAnswer(ctx context.Context, params *types.VoteAnswerParams) error {
-	user, err := s.UserStore.GetUserByID(ctx, params.UserID)
-	if err != nil {
-		return err
-	}
-	
-	answer, err := s.GetAnswerByID(ctx, params.AnswerID)
+// DeleteAnswer removes an answer and everything hanging off it in one
+// transaction: the answer counts of its question and author, its votes,
+// interactions and comments, and the reputation earned or lost on it. The
+// delete itself guards the counters, a second delete finds nothing.
+func (s *MongoAnswerStore) DeleteAnswer(ctx context.Context, answer *types.Answer) error {
+	_, err := withTransaction(ctx, s.client, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		res, err := s.coll.DeleteOne(sessCtx, bson.M{"_id": answer.ID})
+		if err != nil {
+			return nil, storeError(err)
+		}
+
+		if res.DeletedCount == 0 {
+			return nil, ErrNotFound
+		}
+
+		questionUpdate := bson.M{"$inc": bson.M{"answerCount": -1}}
+		if answer.IsAccepted {
+			questionUpdate["$unset"] = bson.M{"acceptedAnswerID": ""}
+		}
+		if _, err := s.database.Collection(QUESTIONCOLL).UpdateOne(sessCtx, bson.M{"_id": answer.QuestionID}, questionUpdate); err != nil {
+			return nil, storeError(err)
+		}
+
+		userUpdate := bson.M{"$inc": bson.M{"answerCount": -1}}
+		if _, err := s.database.Collection(USERCOLL).UpdateOne(sessCtx, bson.M{"_id": answer.UserID}, userUpdate); err != nil {
+			return nil, storeError(err)
+		}
+
+		if _, err := s.database.Collection(VOTECOLL).DeleteMany(sessCtx, bson.M{"targetID": answer.ID}); err != nil {
+			return nil, storeError(err)
+		}
+
+		if _, err := s.database.Collection(INTERACTIONCOLL).DeleteMany(sessCtx, bson.M{"answerID": answer.ID}); err != nil {
+			return nil, storeError(err)
+		}
+
+		if _, err := s.database.Collection(COMMENTCOLL).DeleteMany(sessCtx, bson.M{"postID": answer.ID}); err != nil {
+			return nil, storeError(err)
+		}
+
+		answerID := answer.ID
+		return s.ReputationStore.ReverseEvents(sessCtx, &ReputationEventFilter{AnswerID: &answerID})
+	})
+
+	return err
+}
+
+// DeleteManyAnswersByUserID deletes every answer of a user being deleted
+// through DeleteAnswer, each in its own transaction, so a removal cut short
+// resumes where it stopped.
+func (s *MongoAnswerStore) DeleteManyAnswersByUserID(ctx context.Context, userID primitive.ObjectID) error {
+	var answers []*types.Answer
+
+	cursor, err := s.coll.Find(ctx, bson.M{"userID": userID})
 	if err != nil {
-		return err
+		return storeError(err)
 	}
 
-	filter := bson.M{"_id": answer.ID}
-
-	updateDoc := bson.M{
-		"$pull": bson.M{"upvotes": user.ID},
-		"$addToSet": bson.M{"downvotes": user.ID},
+	if err := cursor.All(ctx, &answers); err != nil {
+		return storeError(err)
 	}
 
-	_, err = s.coll.UpdateOne(ctx, filter, updateDoc)
-	if err != nil {
-		return err
+	for _, answer := range answers {
+		if err := s.DeleteAnswer(ctx, answer); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func (s *MongoAnswerStore) DeleteAnswerByID(ctx context.Context, id string) error {
-	oid, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return err
+// SetRendered caches the rendered answer, unless it was edited since it was
+// rendered.
+func (s *MongoAnswerStore) SetRendered(ctx context.Context, answer *types.Answer, rendered *types.RenderedBody) error {
+	filter := bson.M{"_id": answer.ID, "revision": revisionFilter(rendered.Revision)}
+	_, err := s.coll.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"rendered": rendered}})
+	return storeError(err)
+}
+
+// EditAnswer applies revision to the answer, unless another edit moved the
+// answer past the revision it was based on, which fails with ErrConflict.
+func (s *MongoAnswerStore) EditAnswer(ctx context.Context, revision *types.AnswerRevision) error {
+	updateDoc := bson.M{
+		"$set": bson.M{
+			"content": revision.Description,
+			"lastEditedAt": revision.CreatedAt,
+			"lastEditedBy": revision.EditorID,
+			"revision": revision.Revision,
+		},
 	}
 
-	_, err = s.coll.DeleteOne(ctx, Map{"_id": oid})
+	filter := bson.M{"_id": revision.AnswerID, "revision": previousRevisionFilter(revision.Revision)}
+	res, err := s.coll.UpdateOne(ctx, filter, updateDoc)
 	if err != nil {
-		return err
+		return storeError(err)
+	}
+
+	if res.MatchedCount == 0 {
+		n, err := s.coll.CountDocuments(ctx, bson.M{"_id": revision.AnswerID})
+		if err != nil {
+			return storeError(err)
+		}
+		if n == 0 {
+			return ErrNotFound
+		}
+		return newStoreError(ErrConflict, "answer was edited concurrently")
 	}
 
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,322 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fullstack/dev-overflow/types"
+	"github.com/fullstack/dev-overflow/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const BADGEAWARDCOLL = "badge_awards"
+
+type BadgeStore interface {
+	EnsureIndexes(context.Context) error
+	FindQualifying(context.Context, *types.Badge, time.Time) ([]*types.BadgeAward, error)
+	AwardBadges(context.Context, []*types.BadgeAward) (int64, error)
+	GetBadgeHolderCounts(context.Context) (map[string]int64, error)
+	GetUserBadges(context.Context, primitive.ObjectID) ([]*types.BadgeAward, error)
+	GetRecentAwards(context.Context, *PageParams) (*types.BadgeAwardList, error)
+}
+
+type MongoBadgeStore struct {
+	client *mongo.Client
+	database *mongo.Database
+	coll *mongo.Collection
+}
+
+func NewMongoBadgeStore(client *mongo.Client) *MongoBadgeStore {
+	var mongoenvdbname = os.Getenv("MONGO_DB_NAME")
+	return &MongoBadgeStore{
+		client: client,
+		database: client.Database(mongoenvdbname),
+		coll: client.Database(mongoenvdbname).Collection(BADGEAWARDCOLL),
+	}
+}
+
+// badgeSource describes how criteria read a source collection: who a
+// document belongs to, the post it is about and when it happened.
+type badgeSource struct {
+	coll string
+	userField string
+	postID string
+	postType interface{}
+	dateField string
+}
+
+var badgeSources = map[types.BadgeSource]badgeSource{
+	types.SourceQuestions: {QUESTIONCOLL, "userID", "$_id", bson.M{"$literal": types.VoteOnQuestion}, "createdAt"},
+	types.SourceAnswers: {ANSWERCOLL, "userID", "$_id", bson.M{"$literal": types.VoteOnAnswer}, "createdAt"},
+	types.SourceVotes: {VOTECOLL, "ownerID", "$targetID", "$targetType", "updatedAt"},
+	types.SourceInteractions: {INTERACTIONCOLL, "userID", "$questionID", bson.M{"$literal": types.VoteOnQuestion}, "createdAt"},
+}
+
+func (s *MongoBadgeStore) EnsureIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "key", Value: 1}},
+			Options: options.Index().SetName("badge_award_key").SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "userID", Value: 1}, {Key: "awardedAt", Value: -1}},
+			Options: options.Index().SetName("badge_award_user"),
+		},
+		{
+			Keys: bson.D{{Key: "awardedAt", Value: -1}},
+			Options: options.Index().SetName("badge_award_recent"),
+		},
+	}
+
+	if _, err := s.coll.Indexes().CreateMany(ctx, indexes); err != nil {
+		return storeError(err)
+	}
+
+	return nil
+}
+
+// FindQualifying evaluates the badge criteria as of now and returns an
+// award for everyone who meets them, holders included. Streaks count the
+// Threshold days up to yesterday, so the job must run daily not to miss
+// any.
+func (s *MongoBadgeStore) FindQualifying(ctx context.Context, badge *types.Badge, now time.Time) ([]*types.BadgeAward, error) {
+	var (
+		criteria = badge.Criteria
+		source, ok = badgeSources[criteria.Source]
+		match = bson.M{}
+	)
+	if !ok {
+		return nil, fmt.Errorf("unknown badge source %q", criteria.Source)
+	}
+
+	for field, value := range criteria.Match {
+		match[field] = value
+	}
+
+	userField := source.userField
+	if criteria.UserField != "" {
+		userField = criteria.UserField
+	}
+
+	switch criteria.Kind {
+	case types.CriteriaPost:
+		match[criteria.Field] = bson.M{"$gte": criteria.Threshold}
+	case types.CriteriaStreak:
+		today := types.StartOfDay(now)
+		match[source.dateField] = bson.M{"$gte": today.AddDate(0, 0, -criteria.Threshold), "$lt": today}
+	}
+
+	pipeline := []bson.M{{"$match": match}}
+
+	if criteria.Tag != "" {
+		stages, err := s.tagStages(ctx, criteria)
+		if err != nil {
+			return nil, err
+		}
+		if stages == nil {
+			return []*types.BadgeAward{}, nil
+		}
+		pipeline = append(pipeline, stages...)
+	}
+
+	post := bson.M{"id": source.postID, "type": source.postType}
+	oldestFirst := bson.M{"$sort": bson.D{{Key: source.dateField, Value: 1}, {Key: "_id", Value: 1}}}
+
+	switch criteria.Kind {
+	case types.CriteriaPost:
+		pipeline = append(pipeline,
+			oldestFirst,
+			bson.M{"$project": bson.M{"user": "$" + userField, "post": post}},
+		)
+	case types.CriteriaCount:
+		pipeline = append(pipeline,
+			oldestFirst,
+			bson.M{"$group": bson.M{"_id": "$" + userField, "count": bson.M{"$sum": 1}, "posts": bson.M{"$push": post}}},
+			bson.M{"$match": bson.M{"count": bson.M{"$gte": criteria.Threshold}}},
+			bson.M{"$project": bson.M{"user": "$_id", "post": bson.M{"$arrayElemAt": []any{"$posts", criteria.Threshold - 1}}}},
+		)
+	case types.CriteriaStreak:
+		day := bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$" + source.dateField}}
+		pipeline = append(pipeline,
+			bson.M{"$group": bson.M{"_id": "$" + userField, "days": bson.M{"$addToSet": day}}},
+			bson.M{"$match": bson.M{"$expr": bson.M{"$gte": []any{bson.M{"$size": "$days"}, criteria.Threshold}}}},
+			bson.M{"$project": bson.M{"user": "$_id"}},
+		)
+	default:
+		return nil, fmt.Errorf("unknown badge criteria %q", criteria.Kind)
+	}
+
+	cursor, err := s.database.Collection(source.coll).Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	var results []struct {
+		User primitive.ObjectID `bson:"user"`
+		Post *struct {
+			ID *primitive.ObjectID `bson:"id"`
+			Type string `bson:"type"`
+		} `bson:"post"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, storeError(err)
+	}
+
+	awards := make([]*types.BadgeAward, 0, len(results))
+	for _, result := range results {
+		if result.User.IsZero() {
+			continue
+		}
+
+		var (
+			postID *primitive.ObjectID
+			postType string
+		)
+		if result.Post != nil && result.Post.ID != nil && !result.Post.ID.IsZero() {
+			postID, postType = result.Post.ID, result.Post.Type
+		}
+
+		awards = append(awards, types.NewBadgeAward(badge, result.User, postID, postType, now))
+	}
+
+	return awards, nil
+}
+
+// tagStages keeps the documents about posts whose question carries the
+// criteria tag. It returns nil when the tag does not exist.
+func (s *MongoBadgeStore) tagStages(ctx context.Context, criteria types.BadgeCriteria) ([]bson.M, error) {
+	var tag types.Tag
+	err := s.database.Collection(TAGCOLL).FindOne(ctx, bson.M{"name": utils.FormatTag(criteria.Tag)}).Decode(&tag)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	if criteria.Source == types.SourceQuestions {
+		return []bson.M{{"$match": bson.M{"tags": tag.ID}}}, nil
+	}
+
+	return []bson.M{
+		{"$lookup": bson.M{
+			"from": QUESTIONCOLL,
+			"localField": "questionID",
+			"foreignField": "_id",
+			"as": "taggedQuestion",
+		}},
+		{"$match": bson.M{"taggedQuestion.tags": tag.ID}},
+		{"$project": bson.M{"taggedQuestion": 0}},
+	}, nil
+}
+
+// AwardBadges stores the awards nobody holds yet and returns how many were
+// new. Awarding again is a no-op, so the first award keeps its timestamp
+// and triggering post.
+func (s *MongoBadgeStore) AwardBadges(ctx context.Context, awards []*types.BadgeAward) (int64, error) {
+	if len(awards) == 0 {
+		return 0, nil
+	}
+
+	models := make([]mongo.WriteModel, len(awards))
+	for i, award := range awards {
+		models[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"key": award.Key}).
+			SetUpdate(bson.M{"$setOnInsert": award}).
+			SetUpsert(true)
+	}
+
+	res, err := s.coll.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return 0, storeError(err)
+	}
+
+	return res.UpsertedCount, nil
+}
+
+// GetBadgeHolderCounts returns how many users hold each badge, counting
+// users with several awards of a repeatable badge once.
+func (s *MongoBadgeStore) GetBadgeHolderCounts(ctx context.Context) (map[string]int64, error) {
+	pipeline := []bson.M{
+		{"$group": bson.M{"_id": bson.M{"badgeID": "$badgeID", "userID": "$userID"}}},
+		{"$group": bson.M{"_id": "$_id.badgeID", "holders": bson.M{"$sum": 1}}},
+	}
+
+	cursor, err := s.coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	var results []struct {
+		BadgeID string `bson:"_id"`
+		Holders int64 `bson:"holders"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, storeError(err)
+	}
+
+	counts := make(map[string]int64, len(results))
+	for _, result := range results {
+		counts[result.BadgeID] = result.Holders
+	}
+
+	return counts, nil
+}
+
+func (s *MongoBadgeStore) GetUserBadges(ctx context.Context, userID primitive.ObjectID) ([]*types.BadgeAward, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "awardedAt", Value: -1}, {Key: "_id", Value: -1}})
+
+	awards := []*types.BadgeAward{}
+	cursor, err := s.coll.Find(ctx, bson.M{"userID": userID}, opts)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	if err := cursor.All(ctx, &awards); err != nil {
+		return nil, storeError(err)
+	}
+
+	return awards, nil
+}
+
+// GetRecentAwards returns one page of the latest awards with their holder.
+func (s *MongoBadgeStore) GetRecentAwards(ctx context.Context, params *PageParams) (*types.BadgeAwardList, error) {
+	page, limit, skip := pageBounds(params.Page, params.Limit, "")
+
+	total, err := s.coll.EstimatedDocumentCount(ctx)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	pipeline := []bson.M{
+		{"$sort": bson.D{{Key: "awardedAt", Value: -1}, {Key: "_id", Value: -1}}},
+		{"$skip": skip},
+		{"$limit": limit},
+		lookupPublicUser("userID", "user"),
+		{"$unwind": bson.M{"path": "$user", "preserveNullAndEmptyArrays": true}},
+	}
+
+	cursor, err := s.coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	awards := []*types.BadgeAward{}
+	if err := cursor.All(ctx, &awards); err != nil {
+		return nil, storeError(err)
+	}
+
+	return &types.BadgeAwardList{
+		Awards: awards,
+		Total: total,
+		Page: page,
+		Limit: limit,
+		HasNext: skip+int64(len(awards)) < total,
+	}, nil
+}
@@ -0,0 +1,162 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/fullstack/dev-overflow/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const BOUNTYCOLL = "bounties"
+
+var (
+	ErrBountyExists = newStoreError(ErrConflict, "question already has an open bounty")
+	ErrBountyClosed = newStoreError(ErrConflict, "bounty is no longer open")
+)
+
+type BountyStore interface {
+	CreateBounty(context.Context, *types.Bounty) (*types.Bounty, error)
+	GetOpenBounty(context.Context, primitive.ObjectID) (*types.Bounty, error)
+	GetExpiredBounties(context.Context, time.Time) ([]*types.Bounty, error)
+	CloseBounty(context.Context, *types.Bounty, *types.Answer) (*types.Bounty, error)
+	AwardBounty(context.Context, *types.Bounty, *types.Answer) (*types.Bounty, error)
+}
+
+type MongoBountyStore struct {
+	client *mongo.Client
+	coll *mongo.Collection
+	questionColl *mongo.Collection
+	ReputationStore
+}
+
+func NewMongoBountyStore(client *mongo.Client, reputationStore ReputationStore) *MongoBountyStore {
+	var mongoenvdbname = os.Getenv("MONGO_DB_NAME")
+	return &MongoBountyStore{
+		client: client,
+		coll: client.Database(mongoenvdbname).Collection(BOUNTYCOLL),
+		questionColl: client.Database(mongoenvdbname).Collection(QUESTIONCOLL),
+		ReputationStore: reputationStore,
+	}
+}
+
+// CreateBounty claims the question for the bounty, stores it and takes the
+// amount from the sponsor in one transaction. Only one bounty can be open
+// per question, and the sponsor's reputation must cover the amount when it
+// is taken, which fails with ErrInsufficientReputation.
+func (s *MongoBountyStore) CreateBounty(ctx context.Context, bounty *types.Bounty) (*types.Bounty, error) {
+	bounty.ID = primitive.NewObjectID()
+	bounty.Status = types.BountyOpen
+
+	_, err := withTransaction(ctx, s.client, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		filter := bson.M{"_id": bounty.QuestionID, "bounty": bson.M{"$exists": false}}
+		res, err := s.questionColl.UpdateOne(sessCtx, filter, bson.M{"$set": bson.M{"bounty": bounty.Summary()}})
+		if err != nil {
+			return nil, storeError(err)
+		}
+		if res.MatchedCount == 0 {
+			return nil, ErrBountyExists
+		}
+
+		if _, err := s.coll.InsertOne(sessCtx, bounty); err != nil {
+			return nil, storeError(err)
+		}
+
+		event := types.NewBountyReputationEvent(bounty.SponsorID, types.ReasonBountyOffered, bounty)
+		return s.ReputationStore.SpendReputation(sessCtx, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return bounty, nil
+}
+
+func (s *MongoBountyStore) GetOpenBounty(ctx context.Context, questionID primitive.ObjectID) (*types.Bounty, error) {
+	var bounty types.Bounty
+
+	filter := bson.M{"questionID": questionID, "status": types.BountyOpen}
+	if err := s.coll.FindOne(ctx, filter).Decode(&bounty); err != nil {
+		return nil, storeError(err)
+	}
+
+	return &bounty, nil
+}
+
+func (s *MongoBountyStore) GetExpiredBounties(ctx context.Context, now time.Time) ([]*types.Bounty, error) {
+	bounties := []*types.Bounty{}
+
+	filter := bson.M{"status": types.BountyOpen, "expiresAt": bson.M{"$lte": now}}
+	cursor, err := s.coll.Find(ctx, filter, options.Find().SetSort(bson.M{"expiresAt": 1}))
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	if err := cursor.All(ctx, &bounties); err != nil {
+		return nil, storeError(err)
+	}
+
+	return bounties, nil
+}
+
+// CloseBounty closes an expired bounty, awarding it to answer or letting it
+// lapse when answer is nil. It is used by the expiry job, so it does not
+// care whether the expiry has passed.
+func (s *MongoBountyStore) CloseBounty(ctx context.Context, bounty *types.Bounty, answer *types.Answer) (*types.Bounty, error) {
+	filter := bson.M{"_id": bounty.ID, "status": types.BountyOpen}
+	return s.closeBounty(ctx, filter, bounty, answer, time.Now().UTC())
+}
+
+// AwardBounty is the sponsor's manual award, which is only allowed while the
+// bounty has not expired yet.
+func (s *MongoBountyStore) AwardBounty(ctx context.Context, bounty *types.Bounty, answer *types.Answer) (*types.Bounty, error) {
+	now := time.Now().UTC()
+	filter := bson.M{"_id": bounty.ID, "status": types.BountyOpen, "expiresAt": bson.M{"$gt": now}}
+	return s.closeBounty(ctx, filter, bounty, answer, now)
+}
+
+// closeBounty flips the status of the bounty matched by filter and pays out
+// answer in one transaction. The status is flipped conditionally so a bounty
+// is only ever paid out once.
+func (s *MongoBountyStore) closeBounty(ctx context.Context, filter bson.M, bounty *types.Bounty, answer *types.Answer, now time.Time) (*types.Bounty, error) {
+	update := bson.M{"status": types.BountyExpired, "closedAt": now}
+	if answer != nil {
+		update["status"] = types.BountyAwarded
+		update["answerID"] = answer.ID
+	}
+
+	closed, err := withTransaction(ctx, s.client, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+		var closed types.Bounty
+		if err := s.coll.FindOneAndUpdate(sessCtx, filter, bson.M{"$set": update}, opts).Decode(&closed); err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return nil, ErrBountyClosed
+			}
+			return nil, storeError(err)
+		}
+
+		if _, err := s.questionColl.UpdateOne(sessCtx, bson.M{"_id": bounty.QuestionID}, bson.M{"$unset": bson.M{"bounty": ""}}); err != nil {
+			return nil, storeError(err)
+		}
+
+		if answer != nil {
+			event := types.NewBountyReputationEvent(answer.UserID, types.ReasonBountyAwarded, &closed)
+			if _, err := s.ReputationStore.RecordEvent(sessCtx, event); err != nil {
+				return nil, err
+			}
+		}
+
+		return &closed, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return closed.(*types.Bounty), nil
+}
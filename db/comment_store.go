@@ -0,0 +1,281 @@
+package db
+
+import (
+	"context"
+	"os"
+
+	"github.com/fullstack/dev-overflow/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const COMMENTCOLL = "comments"
+
+type CommentStore interface {
+	EnsureIndexes(context.Context) error
+	CreateComment(context.Context, *types.Comment) (*types.Comment, error)
+	GetCommentByID(context.Context, string) (*types.Comment, error)
+	GetComments(context.Context, primitive.ObjectID, *PageParams) (*types.CommentList, error)
+	GetTopComments(context.Context, []primitive.ObjectID, int) (map[primitive.ObjectID]*types.CommentList, error)
+	GetCommenters(context.Context, primitive.ObjectID) ([]*types.PublicUser, error)
+	EditComment(context.Context, *types.Comment) error
+	UpvoteComment(context.Context, primitive.ObjectID, primitive.ObjectID) error
+	RemoveCommentUpvote(context.Context, primitive.ObjectID, primitive.ObjectID) error
+	DeleteComment(context.Context, primitive.ObjectID) error
+}
+
+type MongoCommentStore struct {
+	client *mongo.Client
+	coll *mongo.Collection
+}
+
+func NewMongoCommentStore(client *mongo.Client) *MongoCommentStore {
+	var mongoenvdbname = os.Getenv("MONGO_DB_NAME")
+	return &MongoCommentStore{
+		client: client,
+		coll: client.Database(mongoenvdbname).Collection(COMMENTCOLL),
+	}
+}
+
+// EnsureIndexes creates the index a post's comments are listed by and the
+// one the question delete cascade removes them by.
+func (s *MongoCommentStore) EnsureIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "postID", Value: 1}, {Key: "createdAt", Value: 1}},
+			Options: options.Index().SetName("comment_post_created"),
+		},
+		{
+			Keys: bson.D{{Key: "questionID", Value: 1}},
+			Options: options.Index().SetName("comment_question"),
+		},
+	}
+
+	if _, err := s.coll.Indexes().CreateMany(ctx, indexes); err != nil {
+		return storeError(err)
+	}
+
+	return nil
+}
+
+func (s *MongoCommentStore) CreateComment(ctx context.Context, comment *types.Comment) (*types.Comment, error) {
+	res, err := s.coll.InsertOne(ctx, comment)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	comment.ID = res.InsertedID.(primitive.ObjectID)
+
+	return comment, nil
+}
+
+func (s *MongoCommentStore) GetCommentByID(ctx context.Context, id string) (*types.Comment, error) {
+	var comments []*types.Comment
+
+	oid, err := objectID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline := []bson.M{
+		{"$match": bson.M{"_id": oid}},
+		lookupPublicUser("userID", "user"),
+		{"$unwind": "$user"},
+	}
+
+	cursor, err := s.coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	if err := cursor.All(ctx, &comments); err != nil {
+		return nil, storeError(err)
+	}
+
+	if len(comments) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return comments[0], nil
+}
+
+// GetComments returns one page of the comments on a post, oldest first.
+func (s *MongoCommentStore) GetComments(ctx context.Context, postID primitive.ObjectID, params *PageParams) (*types.CommentList, error) {
+	page, limit, skip := pageBounds(params.Page, params.Limit, "")
+	filter := bson.M{"postID": postID}
+
+	total, err := s.coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	pipeline := []bson.M{
+		{"$match": filter},
+		{"$sort": bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}},
+		{"$skip": skip},
+		{"$limit": limit},
+		lookupPublicUser("userID", "user"),
+		{"$unwind": "$user"},
+	}
+
+	cursor, err := s.coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	list := &types.CommentList{
+		Comments: []*types.Comment{},
+		Total: total,
+		Page: page,
+		Limit: limit,
+	}
+	if err := cursor.All(ctx, &list.Comments); err != nil {
+		return nil, storeError(err)
+	}
+
+	list.HasNext = skip+int64(len(list.Comments)) < total
+
+	return list, nil
+}
+
+// GetTopComments returns the n most upvoted comments of each post together
+// with the post's comment count.
+func (s *MongoCommentStore) GetTopComments(ctx context.Context, postIDs []primitive.ObjectID, n int) (map[primitive.ObjectID]*types.CommentList, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{"postID": bson.M{"$in": postIDs}}},
+		{"$sort": bson.D{{Key: "upvoteCount", Value: -1}, {Key: "createdAt", Value: 1}}},
+		lookupPublicUser("userID", "user"),
+		{"$unwind": "$user"},
+		{"$group": bson.M{
+			"_id": "$postID",
+			"comments": bson.M{"$push": "$$ROOT"},
+			"total": bson.M{"$sum": 1},
+		}},
+		{"$project": bson.M{
+			"comments": bson.M{"$slice": []any{"$comments", n}},
+			"total": 1,
+		}},
+	}
+
+	cursor, err := s.coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	var groups []struct {
+		PostID primitive.ObjectID `bson:"_id"`
+		Comments []*types.Comment `bson:"comments"`
+		Total int64 `bson:"total"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, storeError(err)
+	}
+
+	top := make(map[primitive.ObjectID]*types.CommentList, len(postIDs))
+	for _, id := range postIDs {
+		top[id] = &types.CommentList{Comments: []*types.Comment{}}
+	}
+	for _, group := range groups {
+		top[group.PostID] = &types.CommentList{
+			Comments: group.Comments,
+			Total: group.Total,
+			HasNext: group.Total > int64(len(group.Comments)),
+		}
+	}
+
+	return top, nil
+}
+
+// GetCommenters returns everyone who commented on a post.
+func (s *MongoCommentStore) GetCommenters(ctx context.Context, postID primitive.ObjectID) ([]*types.PublicUser, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{"postID": postID}},
+		{"$group": bson.M{"_id": "$userID"}},
+		lookupPublicUser("_id", "user"),
+		{"$unwind": "$user"},
+		{"$replaceRoot": bson.M{"newRoot": "$user"}},
+	}
+
+	cursor, err := s.coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	users := []*types.PublicUser{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, storeError(err)
+	}
+
+	return users, nil
+}
+
+func (s *MongoCommentStore) EditComment(ctx context.Context, comment *types.Comment) error {
+	updateDoc := bson.M{"$set": bson.M{
+		"content": comment.Content,
+		"mentions": comment.Mentions,
+		"editedAt": comment.EditedAt,
+	}}
+
+	res, err := s.coll.UpdateOne(ctx, bson.M{"_id": comment.ID}, updateDoc)
+	if err != nil {
+		return storeError(err)
+	}
+
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// UpvoteComment adds the user's upvote. The upvoter is added, and the
+// counter bumped, only when they have not upvoted the comment yet.
+func (s *MongoCommentStore) UpvoteComment(ctx context.Context, id, userID primitive.ObjectID) error {
+	filter := bson.M{"_id": id, "upvotes": bson.M{"$ne": userID}}
+	update := bson.M{"$push": bson.M{"upvotes": userID}, "$inc": bson.M{"upvoteCount": 1}}
+	return s.updateUpvotes(ctx, id, filter, update)
+}
+
+// RemoveCommentUpvote takes the user's upvote back, if they upvoted.
+func (s *MongoCommentStore) RemoveCommentUpvote(ctx context.Context, id, userID primitive.ObjectID) error {
+	filter := bson.M{"_id": id, "upvotes": userID}
+	update := bson.M{"$pull": bson.M{"upvotes": userID}, "$inc": bson.M{"upvoteCount": -1}}
+	return s.updateUpvotes(ctx, id, filter, update)
+}
+
+// updateUpvotes applies update when filter matches. A miss is only an error
+// when the comment is gone, otherwise the upvote was already as asked.
+func (s *MongoCommentStore) updateUpvotes(ctx context.Context, id primitive.ObjectID, filter, update bson.M) error {
+	res, err := s.coll.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return storeError(err)
+	}
+
+	if res.MatchedCount == 0 {
+		n, err := s.coll.CountDocuments(ctx, bson.M{"_id": id})
+		if err != nil {
+			return storeError(err)
+		}
+		if n == 0 {
+			return ErrNotFound
+		}
+	}
+
+	return nil
+}
+
+func (s *MongoCommentStore) DeleteComment(ctx context.Context, id primitive.ObjectID) error {
+	res, err := s.coll.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return storeError(err)
+	}
+
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
@@ -1,5 +1,11 @@
 package db
 
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
 const MongoDBName = "MONGO_DB_NAME"
 
 type Store struct {
@@ -8,6 +14,15 @@ type Store struct {
 	Tag TagStore
 	Answer AnswerStore
 	Interaction InteractionStore
+	Revision RevisionStore
+	Search SearchStore
+	Reputation ReputationStore
+	Bounty BountyStore
+	Comment CommentStore
+	Vote VoteStore
+	VoteAudit VoteAuditStore
+	Quota QuotaStore
+	Badge BadgeStore
 }
 
 type UserQueryParams struct {
@@ -17,4 +32,78 @@ type UserQueryParams struct {
 	SearchQuery string
 }
 
-type Map map[string]any
\ No newline at end of file
+const (
+	defaultPageLimit = 20
+	maxPageLimit = 100
+)
+
+type PageParams struct {
+	Page int64 `query:"page"`
+	Limit int64 `query:"limit"`
+}
+
+// QuestionQueryParams is shared by every question listing. Cursor, when
+// set, takes precedence over Page.
+type QuestionQueryParams struct {
+	Page int64 `query:"page"`
+	Limit int64 `query:"limit"`
+	Cursor string `query:"cursor"`
+	SearchQuery string `query:"q"`
+	Filter string `query:"filter"`
+}
+
+const (
+	AnswerSortVotes = "votes"
+	AnswerSortNewest = "newest"
+	AnswerSortOldest = "oldest"
+	AnswerSortActive = "active"
+)
+
+// AnswerQueryParams is shared by the answer listings. Sort is one of the
+// AnswerSort* values and defaults to votes.
+type AnswerQueryParams struct {
+	Page int64 `query:"page"`
+	Limit int64 `query:"limit"`
+	Sort string `query:"sort"`
+}
+
+// VoteAuditQueryParams lists vote audits, Status narrows them to one of the
+// types.Audit* values.
+type VoteAuditQueryParams struct {
+	Page int64 `query:"page"`
+	Limit int64 `query:"limit"`
+	Status string `query:"status"`
+}
+
+// SearchParams drives the global search. Type limits results to one of the
+// types.SearchType* values.
+type SearchParams struct {
+	Query string `query:"q"`
+	Type string `query:"type"`
+	Page int64 `query:"page"`
+	Limit int64 `query:"limit"`
+}
+
+type Map map[string]any
+
+// withTransaction runs fn in a transaction. When ctx already carries a
+// session, as inside another store's transaction, fn joins it instead of
+// starting a nested one.
+func withTransaction(ctx context.Context, client *mongo.Client, fn func(mongo.SessionContext) (interface{}, error)) (interface{}, error) {
+	if session := mongo.SessionFromContext(ctx); session != nil {
+		return fn(mongo.NewSessionContext(ctx, session))
+	}
+
+	session, err := client.StartSession()
+	if err != nil {
+		return nil, storeError(err)
+	}
+	defer session.EndSession(ctx)
+
+	result, err := session.WithTransaction(ctx, fn)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// The kinds of store errors. Every error returned by a store that callers
+// may act on matches one of them with errors.Is, whatever driver error
+// caused it.
+var (
+	ErrNotFound = errors.New("not found")
+	ErrConflict = errors.New("conflict")
+	ErrInvalidID = errors.New("invalid id")
+	ErrForbidden = errors.New("forbidden")
+)
+
+// storeErr classifies err as kind while keeping err in the chain.
+type storeErr struct {
+	kind error
+	err error
+}
+
+func (e *storeErr) Error() string {
+	return e.err.Error()
+}
+
+func (e *storeErr) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+func newStoreError(kind error, message string) error {
+	return &storeErr{kind: kind, err: errors.New(message)}
+}
+
+// storeError maps driver errors onto the store error kinds. Errors that are
+// already classified, or that have no kind, are returned as is.
+func storeError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	for _, kind := range []error{ErrNotFound, ErrConflict, ErrInvalidID, ErrForbidden} {
+		if errors.Is(err, kind) {
+			return err
+		}
+	}
+
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return &storeErr{kind: ErrNotFound, err: err}
+	case mongo.IsDuplicateKeyError(err):
+		return &storeErr{kind: ErrConflict, err: err}
+	case errors.Is(err, primitive.ErrInvalidHex):
+		return &storeErr{kind: ErrInvalidID, err: err}
+	}
+
+	return err
+}
+
+// objectID parses a hex id, failing with ErrInvalidID.
+func objectID(id string) (primitive.ObjectID, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return primitive.NilObjectID, &storeErr{kind: ErrInvalidID, err: err}
+	}
+	return oid, nil
+}
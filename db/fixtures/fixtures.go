@@ -17,9 +17,6 @@ func AddQuestion(store *db.Store, title string, desc string, userID primitive.Ob
 		Description: desc,
 		UserID: userID,
 		Tags: tags,
-		Upvotes: []primitive.ObjectID{},
-		Downvotes: []primitive.ObjectID{},
-		Answers: []primitive.ObjectID{},
 		CreatedAt: createdAt,
 	}
 
@@ -55,7 +52,6 @@ func AddUser(store *db.Store, firstName string, lastName string, clerkID string,
 func AddTag(store *db.Store, name string) (*types.Tag) {
 	tag := &types.Tag{
 		Name: name,
-		Questions: []primitive.ObjectID{},
 		Followers: []primitive.ObjectID{},
 		CreatedAt: time.Now().UTC(),
 	}
@@ -68,7 +64,7 @@ func AddTag(store *db.Store, name string) (*types.Tag) {
 	return insertedTag
 }
 
-func UpdateTag(store *db.Store, tagID primitive.ObjectID, update *types.UpdateTagQuestionAndFollowers) (*types.Tag, error) {
+func UpdateTag(store *db.Store, tagID primitive.ObjectID, update *types.UpdateTagFollowers) (*types.Tag, error) {
 	if err := store.Tag.UpdateTag(context.Background(), db.Map{"_id":tagID}, update); err != nil {
 		log.Fatal(err)
 	}
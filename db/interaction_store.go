@@ -2,7 +2,6 @@ package db
 
 import (
 	"context"
-	"errors"
 	"os"
 	"time"
 
@@ -10,13 +9,17 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 const INTERACTIONCOLL = "interactions"
 
 type InteractionStore interface {
 	GetInteractionByUserAndQuestionID(context.Context, string, string) (*types.Interaction, error)
+	GetInteractionsByUserID(context.Context, primitive.ObjectID) ([]*types.Interaction, error)
+	CreateInteraction(context.Context, *types.Interaction) (*types.Interaction, error)
 	CreateViewInteraction(context.Context, *types.ViewQuestionParams) (*types.Interaction, error)
+	RecordVisit(context.Context, primitive.ObjectID, time.Time) error
 }
 
 type MongoInteractionStore struct {
@@ -54,7 +57,7 @@ func (s *MongoInteractionStore) GetInteractionByUserAndQuestionID(ctx context.Co
 		if err == mongo.ErrNoDocuments{
 			return nil, nil
 		}
-		return nil, err
+		return nil, storeError(err)
 	}
 
 	return &interaction, nil
@@ -79,19 +82,19 @@ func (s *MongoInteractionStore) CreateViewInteraction(ctx context.Context, param
 	}
 
 	if existingInteraction != nil {
-		return nil, errors.New("user already viewed this question")
+		return nil, newStoreError(ErrConflict, "user already viewed this question")
 	}
 
 	interaction = types.Interaction{
 		UserID: user.ID,
-		Action: "view",
+		Action: types.InteractionView,
 		QuestionID: question.ID,
 		CreatedAt: time.Now().UTC(),
 	}
 
 	res, err := s.coll.InsertOne(ctx, interaction)
 	if err != nil {
-		return nil, err
+		return nil, storeError(err)
 	}
 
 	interaction.ID = res.InsertedID.(primitive.ObjectID)
@@ -99,4 +102,47 @@ func (s *MongoInteractionStore) CreateViewInteraction(ctx context.Context, param
 	_ = s.questionStore.UpdateQuestionViews(ctx, params.QuestionID)
 
 	return &interaction, nil
-}
\ No newline at end of file
+}
+
+func (s *MongoInteractionStore) GetInteractionsByUserID(ctx context.Context, userID primitive.ObjectID) ([]*types.Interaction, error) {
+	interactions := []*types.Interaction{}
+
+	opts := options.Find().SetSort(bson.M{"createdAt": -1})
+	filter := bson.M{"userID": userID, "action": bson.M{"$ne": types.InteractionVisit}}
+	cursor, err := s.coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	if err := cursor.All(ctx, &interactions); err != nil {
+		return nil, storeError(err)
+	}
+
+	return interactions, nil
+}
+
+func (s *MongoInteractionStore) CreateInteraction(ctx context.Context, interaction *types.Interaction) (*types.Interaction, error) {
+	res, err := s.coll.InsertOne(ctx, interaction)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	interaction.ID = res.InsertedID.(primitive.ObjectID)
+
+	return interaction, nil
+}
+
+// RecordVisit records that the user visited on the UTC day of now, at most
+// once a day. Visit streaks are built from these.
+func (s *MongoInteractionStore) RecordVisit(ctx context.Context, userID primitive.ObjectID, now time.Time) error {
+	day := types.StartOfDay(now)
+	filter := bson.M{
+		"userID": userID,
+		"action": types.InteractionVisit,
+		"createdAt": bson.M{"$gte": day},
+	}
+	update := bson.M{"$setOnInsert": bson.M{"createdAt": now}}
+
+	_, err := s.coll.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	return storeError(err)
+}
@@ -0,0 +1,109 @@
+package db
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// publicUserFields projects a users document onto types.PublicUser so the
+// author embedded in questions and answers never carries private fields.
+var publicUserFields = bson.M{
+	"_id": 1,
+	"name": bson.M{"$trim": bson.M{"input": bson.M{"$concat": []any{
+		bson.M{"$ifNull": []any{"$firstName", ""}}, " ", bson.M{"$ifNull": []any{"$lastName", ""}},
+	}}}},
+	"picture": 1,
+	"bio": 1,
+	"location": 1,
+	"portfolioWebsite": 1,
+	"reputation": 1,
+	"questionCount": 1,
+	"answerCount": 1,
+	"joinedAt": 1,
+}
+
+// lookupPublicUser joins the user referenced by localField as a public
+// profile stored under as.
+func lookupPublicUser(localField, as string) bson.M {
+	return bson.M{
+		"$lookup": bson.M{
+			"from": USERCOLL,
+			"let": bson.M{"userID": "$" + localField},
+			"pipeline": []bson.M{
+				{"$match": bson.M{"$expr": bson.M{"$eq": []any{"$_id", "$$userID"}}}},
+				{"$project": publicUserFields},
+			},
+			"as": as,
+		},
+	}
+}
+
+// pageBounds normalizes page and limit and returns the number of documents
+// to skip. A cursor encodes the offset of the next page.
+func pageBounds(page, limit int64, cursor string) (int64, int64, int64) {
+	if limit <= 0 {
+		limit = defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	if offset, ok := decodeCursor(cursor); ok {
+		return offset/limit + 1, limit, offset
+	}
+
+	if page <= 0 {
+		page = 1
+	}
+
+	return page, limit, (page - 1) * limit
+}
+
+func encodeCursor(offset int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("o:" + strconv.FormatInt(offset, 10)))
+}
+
+func decodeCursor(cursor string) (int64, bool) {
+	if cursor == "" {
+		return 0, false
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, false
+	}
+
+	value, found := strings.CutPrefix(string(raw), "o:")
+	if !found {
+		return 0, false
+	}
+
+	offset, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || offset < 0 {
+		return 0, false
+	}
+
+	return offset, true
+}
+
+// revisionFilter matches posts at revision n. Posts that were never edited
+// have no revision field.
+func revisionFilter(n int) bson.M {
+	if n == 0 {
+		return bson.M{"$exists": false}
+	}
+	return bson.M{"$eq": n}
+}
+
+// previousRevisionFilter matches posts at the revision an edit to revision
+// n was based on, so an edit only applies on top of the version it was made
+// from.
+func previousRevisionFilter(n int) bson.M {
+	if n <= 2 {
+		return revisionFilter(0)
+	}
+	return revisionFilter(n - 1)
+}
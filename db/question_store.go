@@ -2,14 +2,17 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
 	"os"
+	"regexp"
+	"time"
 
 	"github.com/fullstack/dev-overflow/types"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 const QUESTIONCOLL = "questions"
@@ -23,33 +26,61 @@ type MongoQuestionStore struct {
 	coll *mongo.Collection
 	TagStore
 	UserStore
+	AnswerStore
+	ReputationStore
 }
 
-func NewMongoQuestionStore(client *mongo.Client, tagStore TagStore, userStore UserStore) *MongoQuestionStore {
+func NewMongoQuestionStore(client *mongo.Client, tagStore TagStore, userStore UserStore, answerStore AnswerStore, reputationStore ReputationStore) *MongoQuestionStore {
 	var mongoenvdbname = os.Getenv("MONGO_DB_NAME")
 	return &MongoQuestionStore{
 		client: client,
 		coll: client.Database(mongoenvdbname).Collection(QUESTIONCOLL),
 		TagStore: tagStore,
 		UserStore: userStore,
+		AnswerStore: answerStore,
+		ReputationStore: reputationStore,
 	}
 }
 
 type QuestionStore interface {
 	Dropper
+	EnsureIndexes(context.Context) error
 	GetQuestionByID(context.Context, string) (*types.Question, error)
-	GetQuestionsByUserID(context.Context, string) ([]*types.Question, error)
-	GetQuestions(context.Context) ([]*types.Question, error)
-	// TODO: IMPLEMENT SAVED QUESTIONS PAKAI PARAMS QUERY *types.SavedQuestionQueryParams
-	GetQuestionsByTagID(context.Context, string) ([]*types.Question, error)
-	GetSavedQuestions(context.Context, string) ([]*types.Question, error)
+	GetQuestionsByUserID(context.Context, string, *QuestionQueryParams) (*types.QuestionList, error)
+	GetQuestions(context.Context, *QuestionQueryParams) (*types.QuestionList, error)
+	GetQuestionsByTagID(context.Context, string, *QuestionQueryParams) (*types.QuestionList, error)
+	GetSavedQuestions(context.Context, string, *QuestionQueryParams) (*types.QuestionList, error)
 	AskQuestion(context.Context, *types.Question) (*types.Question, error)
-	UpvoteQuestion(context.Context, *types.QuestionVoteParams) error
-	DownvoteQuestion(context.Context, *types.QuestionVoteParams) error
+	EditQuestion(context.Context, *types.QuestionRevision) error
 	UpdateQuestionViews(context.Context, string) error
-	UpdateQuestionAnswersField(context.Context, *types.UpdateQuestionAnswersParams) error
-	DeleteQuestionByID(context.Context, string) error
+	DeleteQuestion(context.Context, *types.Question) error
 	DeleteManyQuestionsByUserID(context.Context, primitive.ObjectID) error
+	AddCloseVote(context.Context, primitive.ObjectID, *types.CloseVote) (*types.Question, error)
+	AddReopenVote(context.Context, primitive.ObjectID, primitive.ObjectID) (*types.Question, error)
+	CloseQuestion(context.Context, *types.Question, types.CloseReason, *primitive.ObjectID, []primitive.ObjectID) error
+	ReopenQuestion(context.Context, *types.Question) error
+	SetRendered(context.Context, *types.Question, *types.RenderedBody) error
+}
+
+// EnsureIndexes creates the indexes questions are listed and counted by, per
+// author and per tag.
+func (s *MongoQuestionStore) EnsureIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "userID", Value: 1}},
+			Options: options.Index().SetName("question_user"),
+		},
+		{
+			Keys: bson.D{{Key: "tags", Value: 1}},
+			Options: options.Index().SetName("question_tags"),
+		},
+	}
+
+	if _, err := s.coll.Indexes().CreateMany(ctx, indexes); err != nil {
+		return storeError(err)
+	}
+
+	return nil
 }
 
 func (s *MongoQuestionStore) Drop(ctx context.Context) error {
@@ -60,7 +91,7 @@ func (s *MongoQuestionStore) Drop(ctx context.Context) error {
 func (s *MongoQuestionStore) GetQuestionByID(ctx context.Context, id string) (*types.Question, error) {
 	var question types.Question
 
-	oid, err := primitive.ObjectIDFromHex(id)
+	oid, err := objectID(id)
 	if err != nil {
 		return nil, err
 	}
@@ -69,14 +100,7 @@ func (s *MongoQuestionStore) GetQuestionByID(ctx context.Context, id string) (*t
 		{
 			"$match": bson.M{"_id": oid},
 		},
-		{
-			"$lookup": bson.M{
-				"from": "users",
-				"localField": "userID",
-				"foreignField": "_id",
-				"as": "user",
-			},
-		},
+		lookupPublicUser("userID", "user"),
 		{
 			"$unwind": "$user",
 		},
@@ -92,360 +116,442 @@ func (s *MongoQuestionStore) GetQuestionByID(ctx context.Context, id string) (*t
 
 	cursor, err := s.coll.Aggregate(ctx, pipeline)
 	if err != nil {
-		return nil, err
+		return nil, storeError(err)
 	}
 
 	defer cursor.Close(ctx)
 	if cursor.Next(ctx) {
 		if err := cursor.Decode(&question); err != nil {
-			return nil, err
+			return nil, storeError(err)
 		}
 	} else {
-		return nil, mongo.ErrNoDocuments
+		return nil, ErrNotFound
 	}
 
+	var duplicateOfTitle string
+	if question.DuplicateOf != nil {
+		var original types.Question
+		if err := s.coll.FindOne(ctx, bson.M{"_id": *question.DuplicateOf}).Decode(&original); err == nil {
+			duplicateOfTitle = original.Title
+		}
+	}
+	question.Notice = types.NewQuestionNotice(&question, duplicateOfTitle)
+
 	return &question, nil
 }
 
-func (s *MongoQuestionStore) GetQuestionsByUserID(ctx context.Context, id string) ([]*types.Question, error) {
-	var questions []*types.Question
-
+func (s *MongoQuestionStore) GetQuestionsByUserID(ctx context.Context, id string, params *QuestionQueryParams) (*types.QuestionList, error) {
 	user, err := s.UserStore.GetUserByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	oid := user.ID
+	return s.listQuestions(ctx, bson.M{"userID": user.ID}, params)
+}
 
-	pipeline := []bson.M{
-		{
-			"$match": bson.M{"_id": oid},
-		},
-		{
-			"$lookup": bson.M{
-				"from": "users",
-				"localField": "userID",
-				"foreignField": "_id",
-				"as": "user",
-			},
-		},
-		{
-			"$unwind": "$user",
-		},
-		{
-			"$lookup": bson.M{
-				"from": "tags",
-				"localField": "tags",
-				"foreignField": "_id",
-				"as": "tagDetails",	
-			},
-		},
-		{
-			"$sort": bson.M{"upvotes": -1, "views": -1},
-		},
-	}
+func (s *MongoQuestionStore) GetQuestions(ctx context.Context, params *QuestionQueryParams) (*types.QuestionList, error) {
+	return s.listQuestions(ctx, bson.M{}, params)
+}
 
-	cursor, err := s.coll.Aggregate(ctx, pipeline)
+func (s *MongoQuestionStore) GetSavedQuestions(ctx context.Context, id string, params *QuestionQueryParams) (*types.QuestionList, error) {
+	user, err := s.UserStore.GetUserByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	defer cursor.Close(ctx)
+	saved := user.Saved
+	if saved == nil {
+		saved = []primitive.ObjectID{}
+	}
 
-	for cursor.Next(ctx) {
-		var question types.Question
-		if err := cursor.Decode(&question); err != nil {
-			return nil, err
-		}
+	return s.listQuestions(ctx, bson.M{"_id": bson.M{"$in": saved}}, params)
+}
 
-		questions = append(questions, &question)
+func (s *MongoQuestionStore) GetQuestionsByTagID(ctx context.Context, id string, params *QuestionQueryParams) (*types.QuestionList, error) {
+	tag, err := s.TagStore.GetTagByID(ctx, id)
+	if err != nil {
+		return nil, err
 	}
 
-	return questions, nil
-
+	return s.listQuestions(ctx, bson.M{"tags": tag.ID}, params)
 }
 
-func (s *MongoQuestionStore) GetQuestions(ctx context.Context) ([]*types.Question, error) {
-	var questions []*types.Question
+// listQuestions returns one page of the questions matching match, narrowed
+// down by the search query and filter of params.
+func (s *MongoQuestionStore) listQuestions(ctx context.Context, match bson.M, params *QuestionQueryParams) (*types.QuestionList, error) {
+	page, limit, skip := pageBounds(params.Page, params.Limit, params.Cursor)
+
+	conditions := []bson.M{match}
+
+	if params.SearchQuery != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(params.SearchQuery), Options: "i"}
+		conditions = append(conditions, bson.M{"$or": []bson.M{
+			{"title": pattern},
+			{"description": pattern},
+		}})
+	}
+
+	sort := bson.D{{Key: "createdAt", Value: -1}}
+
+	switch params.Filter {
+	case "most_voted":
+		sort = bson.D{{Key: "score", Value: -1}, {Key: "createdAt", Value: -1}}
+	case "most_viewed":
+		sort = bson.D{{Key: "views", Value: -1}, {Key: "createdAt", Value: -1}}
+	case "frequent":
+		sort = bson.D{{Key: "answerCount", Value: -1}, {Key: "views", Value: -1}, {Key: "createdAt", Value: -1}}
+	case "unanswered":
+		conditions = append(conditions, bson.M{"answerCount": 0})
+	case "solved":
+		conditions = append(conditions, bson.M{"acceptedAnswerID": bson.M{"$type": "objectId"}})
+	case "unsolved":
+		conditions = append(conditions, bson.M{"acceptedAnswerID": bson.M{"$not": bson.M{"$type": "objectId"}}})
+	case "featured":
+		conditions = append(conditions, bson.M{"bounty": bson.M{"$exists": true}})
+		sort = bson.D{{Key: "bounty.amount", Value: -1}, {Key: "bounty.expiresAt", Value: 1}}
+	}
 
 	pipeline := []bson.M{
-		{
-			"$lookup": bson.M{
-				"from": "users",
-				"localField": "userID",
-				"foreignField": "_id",
-				"as": "user",
-			}},
-			{"$unwind":"$user"},
-			{"$lookup":bson.M{
-				"from": "tags",
-				"localField": "tags",
-				"foreignField": "_id",
-				"as": "tagDetails",
-			}},
-			{"$sort":bson.M{"createdAt":-1}},
+		{"$match": bson.M{"$and": conditions}},
+		{"$sort": sort},
+		{"$facet": bson.M{
+			"metadata": []bson.M{{"$count": "total"}},
+			"questions": []bson.M{
+				{"$skip": skip},
+				{"$limit": limit},
+				lookupPublicUser("userID", "user"),
+				{"$unwind": "$user"},
+				{"$lookup": bson.M{
+					"from": TAGCOLL,
+					"localField": "tags",
+					"foreignField": "_id",
+					"as": "tagDetails",
+				}},
+			},
+		}},
 	}
 
 	cursor, err := s.coll.Aggregate(ctx, pipeline)
 	if err != nil {
-		return nil, err
+		return nil, storeError(err)
 	}
 
 	defer cursor.Close(ctx)
 
-	for cursor.Next(ctx) {
-		var question types.Question
-		if err := cursor.Decode(&question); err != nil {
-			return nil, err
+	var result struct {
+		Metadata []struct {
+			Total int64 `bson:"total"`
+		} `bson:"metadata"`
+		Questions []*types.Question `bson:"questions"`
+	}
+
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&result); err != nil {
+			return nil, storeError(err)
 		}
+	}
 
-		questions = append(questions, &question)
+	list := &types.QuestionList{
+		Questions: result.Questions,
+		Page: page,
+		Limit: limit,
 	}
 
-	log.Println(questions)
-	return questions, nil
-}
+	if list.Questions == nil {
+		list.Questions = []*types.Question{}
+	}
 
-func (s *MongoQuestionStore) GetSavedQuestions(ctx context.Context, id string) ([]*types.Question, error) {
-	var questions []*types.Question
-	
-	user, err := s.UserStore.GetUserByID(ctx, id)
-	if err != nil {
-		return nil, err
+	if len(result.Metadata) > 0 {
+		list.Total = result.Metadata[0].Total
 	}
 
-	for _, question := range user.Saved {
-		pipeline := []bson.M{
-			{
-				"$match": bson.M{"_id": question},
-			},
-			// {
-			// 	"$match": bson.M{"title": bson.M{"$regex": primitive.Regex{Pattern: params.SearchQuery, Options: "i"}}},
-			// },
-			{
-				"$lookup": bson.M{
-					"from": "users",
-					"localField": "userID",
-					"foreignField": "_id",
-					"as": "user",
-				},
-			},
-			{
-				"$unwind": "$user",
-			},
-			{
-				"$lookup": bson.M{
-					"from": "tags",
-					"localField": "tags",
-					"foreignField": "_id",
-					"as": "tagDetails",
-				},
-			},
-			{"$sort":bson.M{"createdAt":-1}},
-		}
+	if skip+int64(len(list.Questions)) < list.Total {
+		list.HasNext = true
+		list.NextCursor = encodeCursor(skip + limit)
+	}
 
-		cursor, err := s.coll.Aggregate(ctx, pipeline)
+	return list, nil
+}
+
+// AskQuestion stores the question and updates the counters of its tags and
+// author in one transaction.
+func (s *MongoQuestionStore) AskQuestion(ctx context.Context, question *types.Question) (*types.Question, error) {
+	_, err := withTransaction(ctx, s.client, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		res, err := s.coll.InsertOne(sessCtx, question)
 		if err != nil {
-			return nil, err
+			return nil, storeError(err)
 		}
 
-		var question types.Question
-		if cursor.Next(ctx) {
-			if err := cursor.Decode(&question); err != nil {
+		question.ID = res.InsertedID.(primitive.ObjectID)
+
+		for _, tag := range question.Tags {
+			if err := s.TagStore.UpdateTag(sessCtx, Map{"_id": tag}, &types.UpdateTagFollowers{Followers: question.UserID}); err != nil {
 				return nil, err
 			}
-			questions = append(questions, &question)
-		} 
-	}
-
-	// startIndex := (params.Page - 1) * params.Limit
-	// endIndex := params.Page + params.Limit
-
-	// if startIndex > int64(len(questions)) {
-	// 	startIndex = int64(len(questions))
-	// }
+		}
 
-	// if endIndex > int64(len(questions)) {
-	// 	endIndex = int64(len(questions))
-	// }
+		if err := s.TagStore.CountTagQuestions(sessCtx, question.Tags); err != nil {
+			return nil, err
+		}
 
-	// pagedQuestions := questions[startIndex:endIndex]
+		return nil, s.UserStore.IncrementQuestionCount(sessCtx, question.UserID, 1)
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	// return pagedQuestions, nil
-	return questions, nil
+	return question, nil
 }
 
-func (s *MongoQuestionStore) GetQuestionsByTagID(ctx context.Context, id string) ([]*types.Question, error) {
-	var questions []*types.Question
+// EditQuestion applies revision to the question, unless another edit moved
+// the question past the revision it was based on, which fails with
+// ErrConflict.
+func (s *MongoQuestionStore) EditQuestion(ctx context.Context, revision *types.QuestionRevision) error {
+	filter := bson.M{"_id": revision.QuestionID, "revision": previousRevisionFilter(revision.Revision)}
 
-	tag, err := s.TagStore.GetTagByID(ctx, id)
-	if err != nil {
-		return nil, err
+	updateDoc := bson.M{
+		"$set": bson.M{
+			"title": revision.Title,
+			"description": revision.Description,
+			"tags": revision.Tags,
+			"lastEditedAt": revision.CreatedAt,
+			"lastEditedBy": revision.EditorID,
+			"revision": revision.Revision,
+		},
 	}
 
-	pipeline := []bson.M{
-		{"$match": bson.M{"tags": tag.ID}},
-		{
-			"$lookup": bson.M{
-				"from": "users",
-				"localField": "userID",
-				"foreignField": "_id",
-				"as": "user",
-			}},
-		{"$unwind": "$user"},
-		{"$lookup": bson.M{
-			"from": "tags",
-			"localField": "tags",
-			"foreignField": "_id",
-			"as": "tagDetails",
-		}},
-		{"$sort": bson.M{"createdAt": -1}},
+	res, err := s.coll.UpdateOne(ctx, filter, updateDoc)
+	if err != nil {
+		return storeError(err)
 	}
 
-	cursor, err := s.coll.Aggregate(ctx, pipeline)
-	if err != nil {
-		return nil, err
+	if res.MatchedCount == 0 {
+		return s.editConflict(ctx, revision.QuestionID)
 	}
 
-	defer cursor.Close(ctx)
+	return nil
+}
 
-	for cursor.Next(ctx) {
-		var question types.Question
-		if err := cursor.Decode(&question); err != nil {
-			return nil, err
-		}
+// editConflict tells a missing question from one edited concurrently.
+func (s *MongoQuestionStore) editConflict(ctx context.Context, id primitive.ObjectID) error {
+	n, err := s.coll.CountDocuments(ctx, bson.M{"_id": id})
+	if err != nil {
+		return storeError(err)
+	}
 
-		questions = append(questions, &question)
+	if n == 0 {
+		return ErrNotFound
 	}
 
-	return questions, nil
+	return newStoreError(ErrConflict, "question was edited concurrently")
 }
 
-func (s *MongoQuestionStore) AskQuestion(ctx context.Context, question *types.Question) (*types.Question, error) {
-	res, err := s.coll.InsertOne(ctx, question)
+func (s *MongoQuestionStore) UpdateQuestionViews(ctx context.Context, id string) error {
+	question, err := s.GetQuestionByID(ctx, id)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	question.ID = res.InsertedID.(primitive.ObjectID)
+	filter := bson.M{"_id": question.ID}
 
-	for _, tag := range question.Tags {
-		if err := s.TagStore.UpdateTag(ctx, Map{"_id": tag}, &types.UpdateTagQuestionAndFollowers{Questions: question.ID, Followers: question.UserID}); err != nil {
-			return nil, err
-		}
-	}
+	_ = s.coll.FindOneAndUpdate(ctx, filter, bson.M{"$inc": bson.M{"views": 1}})
 
-	_ = s.UserStore.UpdateUserQuestionsField(ctx, question.UserID, question.ID)
+	return nil
 
-	return question, nil
 }
 
-func (s *MongoQuestionStore) UpvoteQuestion(ctx context.Context, params *types.QuestionVoteParams) error {
-	user, err := s.UserStore.GetUserByID(ctx, params.UserID)
-	if err != nil {
-		return err
+// AddCloseVote records a vote to close an open question. It returns
+// ErrNotFound when the question is closed or the user already
+// voted.
+func (s *MongoQuestionStore) AddCloseVote(ctx context.Context, questionID primitive.ObjectID, vote *types.CloseVote) (*types.Question, error) {
+	filter := bson.M{
+		"_id": questionID,
+		"closedAt": bson.M{"$exists": false},
+		"closeVotes.userID": bson.M{"$ne": vote.UserID},
 	}
 
-	question, err := s.GetQuestionByID(ctx, params.QuestionID)
-	if err != nil {
-		return err
+	return s.addVote(ctx, filter, bson.M{"$push": bson.M{"closeVotes": vote}})
+}
+
+// AddReopenVote records a vote to reopen a closed question. It returns
+// ErrNotFound when the question is open or the user already voted.
+func (s *MongoQuestionStore) AddReopenVote(ctx context.Context, questionID, userID primitive.ObjectID) (*types.Question, error) {
+	filter := bson.M{
+		"_id": questionID,
+		"closedAt": bson.M{"$exists": true},
+		"reopenVotes": bson.M{"$ne": userID},
 	}
 
-	filter := bson.M{"_id": question.ID}
+	return s.addVote(ctx, filter, bson.M{"$push": bson.M{"reopenVotes": userID}})
+}
 
-	updateDoc := bson.M{
-		"$pull": bson.M{"downvotes": user.ID},
-		"$addToSet": bson.M{"upvotes": user.ID},
-	}
+func (s *MongoQuestionStore) addVote(ctx context.Context, filter, update bson.M) (*types.Question, error) {
+	var question types.Question
 
-	_, err = s.coll.UpdateOne(ctx, filter, updateDoc)
-	if err != nil {
-		return err
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if err := s.coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&question); err != nil {
+		return nil, storeError(err)
 	}
 
-	return nil
+	return &question, nil
 }
 
-func (s *MongoQuestionStore) DownvoteQuestion(ctx context.Context, params *types.QuestionVoteParams) error {
-	user, err := s.UserStore.GetUserByID(ctx, params.UserID)
-	if err != nil {
-		return err
+// CloseQuestion closes an open question and, for duplicates, links the
+// original back to it.
+func (s *MongoQuestionStore) CloseQuestion(ctx context.Context, question *types.Question, reason types.CloseReason, duplicateOf *primitive.ObjectID, closedBy []primitive.ObjectID) error {
+	set := bson.M{
+		"closedAt": time.Now().UTC(),
+		"closeReason": reason,
+		"closedBy": closedBy,
 	}
-
-	question, err := s.GetQuestionByID(ctx, params.QuestionID)
-	if err != nil {
-		return err
+	if duplicateOf != nil {
+		set["duplicateOf"] = *duplicateOf
 	}
 
-	filter := bson.M{"_id": question.ID}
-
+	filter := bson.M{"_id": question.ID, "closedAt": bson.M{"$exists": false}}
 	updateDoc := bson.M{
-		"$pull": bson.M{"upvotes": user.ID},
-		"$addToSet": bson.M{"downvotes": user.ID},
+		"$set": set,
+		"$unset": bson.M{"closeVotes": "", "reopenVotes": ""},
 	}
 
-	_, err = s.coll.UpdateOne(ctx, filter, updateDoc)
+	res, err := s.coll.UpdateOne(ctx, filter, updateDoc)
 	if err != nil {
-		return err
+		return storeError(err)
 	}
 
-	return nil
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
 
+	if duplicateOf != nil {
+		_, err = s.coll.UpdateOne(ctx, bson.M{"_id": *duplicateOf}, bson.M{"$addToSet": bson.M{"duplicates": question.ID}})
+	}
+
+	return storeError(err)
 }
 
-func (s *MongoQuestionStore) UpdateQuestionViews(ctx context.Context, id string) error {
-	question, err := s.GetQuestionByID(ctx, id)
+// ReopenQuestion reopens a closed question and unlinks it from the original
+// it duplicated.
+func (s *MongoQuestionStore) ReopenQuestion(ctx context.Context, question *types.Question) error {
+	filter := bson.M{"_id": question.ID, "closedAt": bson.M{"$exists": true}}
+	updateDoc := bson.M{"$unset": bson.M{
+		"closedAt": "",
+		"closeReason": "",
+		"closedBy": "",
+		"duplicateOf": "",
+		"closeVotes": "",
+		"reopenVotes": "",
+	}}
+
+	res, err := s.coll.UpdateOne(ctx, filter, updateDoc)
 	if err != nil {
-		return err
+		return storeError(err)
 	}
 
-	filter := bson.M{"_id": question.ID}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
 
-	_ = s.coll.FindOneAndUpdate(ctx, filter, bson.M{"$inc": bson.M{"views": 1}})
+	if question.DuplicateOf != nil {
+		_, err = s.coll.UpdateOne(ctx, bson.M{"_id": *question.DuplicateOf}, bson.M{"$pull": bson.M{"duplicates": question.ID}})
+	}
 
-	return nil
+	return storeError(err)
+}
 
+// SetRendered caches the rendered description, unless the question was
+// edited since it was rendered.
+func (s *MongoQuestionStore) SetRendered(ctx context.Context, question *types.Question, rendered *types.RenderedBody) error {
+	filter := bson.M{"_id": question.ID, "revision": revisionFilter(rendered.Revision)}
+	_, err := s.coll.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"rendered": rendered}})
+	return storeError(err)
 }
 
-func (s *MongoQuestionStore) UpdateQuestionAnswersField(ctx context.Context, update *types.UpdateQuestionAnswersParams) error {
-	question, err := s.GetQuestionByID(ctx, update.QuestionID)
-	if err != nil {
-		return err
-	}
+// DeleteQuestion removes a question and everything hanging off it in one
+// transaction: its answers through DeleteAnswer, the votes, interactions
+// and comments on it, the counters of its author and tags, and the
+// reputation earned or lost on it. Reversing the question's reputation also
+// refunds the sponsor of an open bounty, which is cancelled so the expiry
+// job leaves it alone. The delete itself guards the cascade, a second
+// delete finds nothing.
+func (s *MongoQuestionStore) DeleteQuestion(ctx context.Context, question *types.Question) error {
+	_, err := withTransaction(ctx, s.client, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		res, err := s.coll.DeleteOne(sessCtx, bson.M{"_id": question.ID})
+		if err != nil {
+			return nil, storeError(err)
+		}
 
-	filter := bson.M{"_id": question.ID}
+		if res.DeletedCount == 0 {
+			return nil, ErrNotFound
+		}
 
-	updateDoc := bson.M{
-		"$push": bson.M{
-			"answers": bson.M{"$each":[]primitive.ObjectID{update.Answers}},
-		},
-	}
+		answers, err := s.AnswerStore.GetAllAnswersOfQuestion(sessCtx, question.ID)
+		if err != nil {
+			return nil, err
+		}
 
-	_, err = s.coll.UpdateOne(ctx, filter, updateDoc)
-	if err != nil {
-		return err
-	}
+		for _, answer := range answers {
+			if err := s.AnswerStore.DeleteAnswer(sessCtx, answer); err != nil {
+				return nil, err
+			}
+		}
 
-	return nil
+		database := s.coll.Database()
+
+		userUpdate := bson.M{"$inc": bson.M{"questionCount": -1}}
+		if _, err := database.Collection(USERCOLL).UpdateOne(sessCtx, bson.M{"_id": question.UserID}, userUpdate); err != nil {
+			return nil, storeError(err)
+		}
+
+		if err := s.TagStore.CountTagQuestions(sessCtx, question.Tags); err != nil {
+			return nil, err
+		}
+
+		if _, err := database.Collection(VOTECOLL).DeleteMany(sessCtx, bson.M{"targetID": question.ID}); err != nil {
+			return nil, storeError(err)
+		}
+
+		if _, err := database.Collection(INTERACTIONCOLL).DeleteMany(sessCtx, bson.M{"questionID": question.ID}); err != nil {
+			return nil, storeError(err)
+		}
+
+		if _, err := database.Collection(COMMENTCOLL).DeleteMany(sessCtx, bson.M{"questionID": question.ID}); err != nil {
+			return nil, storeError(err)
+		}
+
+		bountyFilter := bson.M{"questionID": question.ID, "status": types.BountyOpen}
+		bountyUpdate := bson.M{"$set": bson.M{"status": types.BountyCancelled, "closedAt": time.Now().UTC()}}
+		if _, err := database.Collection(BOUNTYCOLL).UpdateMany(sessCtx, bountyFilter, bountyUpdate); err != nil {
+			return nil, storeError(err)
+		}
+
+		questionID := question.ID
+		return s.ReputationStore.ReverseEvents(sessCtx, &ReputationEventFilter{QuestionID: &questionID})
+	})
+
+	return err
 }
 
-func (s *MongoQuestionStore) DeleteQuestionByID(ctx context.Context, id string) error {
+// DeleteManyQuestionsByUserID deletes every question of a user being
+// deleted through DeleteQuestion, each in its own transaction, so a removal
+// cut short resumes where it stopped.
+func (s *MongoQuestionStore) DeleteManyQuestionsByUserID(ctx context.Context, id primitive.ObjectID) error {
+	var questions []*types.Question
 
-	oid,err := primitive.ObjectIDFromHex(id)
+	cursor, err := s.coll.Find(ctx, bson.M{"userID": id}, options.Find().SetProjection(bson.M{"_id": 1, "userID": 1, "tags": 1}))
 	if err != nil {
-		return err
+		return storeError(err)
 	}
 
-	if err := s.coll.FindOneAndDelete(ctx, bson.M{"_id": oid}).Err(); err != nil {
-		return err
+	if err := cursor.All(ctx, &questions); err != nil {
+		return storeError(err)
 	}
-	return nil
-}
 
-func (s *MongoQuestionStore) DeleteManyQuestionsByUserID(ctx context.Context, id primitive.ObjectID) error {
-	_, err := s.coll.DeleteMany(ctx, bson.M{"userID": id})
-	if err != nil {
-		return err
+	for _, question := range questions {
+		if err := s.DeleteQuestion(ctx, question); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
 	}
+
 	return nil
 }
\ No newline at end of file
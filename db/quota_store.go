@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/fullstack/dev-overflow/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const QUOTACOLL = "quotas"
+
+// ErrQuotaExceeded is returned when a daily quota is used up.
+var ErrQuotaExceeded = newStoreError(ErrConflict, "daily quota exceeded")
+
+type QuotaStore interface {
+	EnsureIndexes(context.Context) error
+	ConsumeQuota(context.Context, primitive.ObjectID, types.QuotaKind, int) error
+	RefundQuota(context.Context, primitive.ObjectID, types.QuotaKind) error
+	GetQuotaUsage(context.Context, primitive.ObjectID) (map[types.QuotaKind]int, error)
+}
+
+// MongoQuotaStore counts each user's actions in one document per user and
+// UTC day, removed by a TTL index once the day is over.
+type MongoQuotaStore struct {
+	client *mongo.Client
+	coll *mongo.Collection
+}
+
+func NewMongoQuotaStore(client *mongo.Client) *MongoQuotaStore {
+	var mongoenvdbname = os.Getenv("MONGO_DB_NAME")
+	return &MongoQuotaStore{
+		client: client,
+		coll: client.Database(mongoenvdbname).Collection(QUOTACOLL),
+	}
+}
+
+func (s *MongoQuotaStore) EnsureIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "userID", Value: 1}, {Key: "day", Value: 1}},
+			Options: options.Index().SetName("quota_user_day").SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetName("quota_expiry").SetExpireAfterSeconds(0),
+		},
+	}
+
+	if _, err := s.coll.Indexes().CreateMany(ctx, indexes); err != nil {
+		return storeError(err)
+	}
+
+	return nil
+}
+
+// ConsumeQuota counts one kind action of the user today, or fails with
+// ErrQuotaExceeded once limit actions were counted. A negative limit is unlimited.
+// The check and the count are a single upsert: when the day's document
+// exists but is at the limit the filter misses, and the upsert then
+// collides with it on the unique index.
+func (s *MongoQuotaStore) ConsumeQuota(ctx context.Context, userID primitive.ObjectID, kind types.QuotaKind, limit int) error {
+	if limit == 0 {
+		return ErrQuotaExceeded
+	}
+
+	now := time.Now().UTC()
+	field := "counts." + string(kind)
+
+	filter := bson.M{"userID": userID, "day": types.QuotaDay(now)}
+	if limit >= 0 {
+		filter[field] = bson.M{"$not": bson.M{"$gte": limit}}
+	}
+
+	update := bson.M{
+		"$inc": bson.M{field: 1},
+		"$setOnInsert": bson.M{"expiresAt": types.QuotaReset(now)},
+	}
+
+	_, err := s.coll.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrQuotaExceeded
+	}
+
+	return storeError(err)
+}
+
+// RefundQuota gives back one kind action counted today, for actions that
+// failed after their quota was consumed.
+func (s *MongoQuotaStore) RefundQuota(ctx context.Context, userID primitive.ObjectID, kind types.QuotaKind) error {
+	field := "counts." + string(kind)
+	filter := bson.M{"userID": userID, "day": types.QuotaDay(time.Now()), field: bson.M{"$gt": 0}}
+
+	_, err := s.coll.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{field: -1}})
+	return storeError(err)
+}
+
+// GetQuotaUsage returns what the user used of each quota today.
+func (s *MongoQuotaStore) GetQuotaUsage(ctx context.Context, userID primitive.ObjectID) (map[types.QuotaKind]int, error) {
+	var usage struct {
+		Counts map[types.QuotaKind]int `bson:"counts"`
+	}
+
+	err := s.coll.FindOne(ctx, bson.M{"userID": userID, "day": types.QuotaDay(time.Now())}).Decode(&usage)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, storeError(err)
+	}
+
+	if usage.Counts == nil {
+		usage.Counts = map[types.QuotaKind]int{}
+	}
+
+	return usage.Counts, nil
+}
@@ -0,0 +1,348 @@
+package db
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/fullstack/dev-overflow/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const REPUTATIONCOLL = "reputation_events"
+
+var ErrInsufficientReputation = newStoreError(ErrForbidden, "not enough reputation")
+
+// ReputationEventFilter selects ledger events. QuestionOnly restricts the
+// match to events about the question itself rather than its answers.
+type ReputationEventFilter struct {
+	ActorID *primitive.ObjectID
+	QuestionID *primitive.ObjectID
+	AnswerID *primitive.ObjectID
+	QuestionOnly bool
+	Reasons []types.ReputationReason
+}
+
+type ReputationStore interface {
+	EnsureIndexes(context.Context) error
+	RecordEvent(context.Context, *types.ReputationEvent) (*types.ReputationEvent, error)
+	SpendReputation(context.Context, *types.ReputationEvent) (*types.ReputationEvent, error)
+	ReverseEvents(context.Context, *ReputationEventFilter) ([]*types.ReputationEvent, error)
+	RecordVote(context.Context, types.PostRef, primitive.ObjectID, types.VoteState, types.VoteState) error
+	GetReputationHistory(context.Context, primitive.ObjectID, *PageParams) (*types.ReputationHistory, error)
+	RecomputeReputation(context.Context) (int64, error)
+}
+
+type MongoReputationStore struct {
+	client *mongo.Client
+	coll *mongo.Collection
+	userColl *mongo.Collection
+}
+
+func NewMongoReputationStore(client *mongo.Client) *MongoReputationStore {
+	var mongoenvdbname = os.Getenv("MONGO_DB_NAME")
+	return &MongoReputationStore{
+		client: client,
+		coll: client.Database(mongoenvdbname).Collection(REPUTATIONCOLL),
+		userColl: client.Database(mongoenvdbname).Collection(USERCOLL),
+	}
+}
+
+// EnsureIndexes creates the unique index on reverses, so an event can only
+// ever be reversed once, and the index the history is read by.
+func (s *MongoReputationStore) EnsureIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "reverses", Value: 1}},
+			Options: options.Index().SetName("reputation_reverses").SetUnique(true).SetSparse(true),
+		},
+		{
+			Keys: bson.D{{Key: "userID", Value: 1}, {Key: "createdAt", Value: -1}},
+			Options: options.Index().SetName("reputation_user_created"),
+		},
+	}
+
+	if _, err := s.coll.Indexes().CreateMany(ctx, indexes); err != nil {
+		return storeError(err)
+	}
+
+	return nil
+}
+
+// RecordEvent appends the event to the ledger and applies its points to the
+// user's total in one transaction.
+func (s *MongoReputationStore) RecordEvent(ctx context.Context, event *types.ReputationEvent) (*types.ReputationEvent, error) {
+	_, err := withTransaction(ctx, s.client, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, s.recordEvent(sessCtx, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+func (s *MongoReputationStore) recordEvent(ctx context.Context, event *types.ReputationEvent) error {
+	res, err := s.coll.InsertOne(ctx, event)
+	if err != nil {
+		return storeError(err)
+	}
+
+	event.ID = res.InsertedID.(primitive.ObjectID)
+
+	if _, err := s.userColl.UpdateOne(ctx, bson.M{"_id": event.UserID}, bson.M{"$inc": bson.M{"reputation": event.Points}}); err != nil {
+		return storeError(err)
+	}
+
+	return nil
+}
+
+// SpendReputation records an event that takes reputation from its user,
+// failing with ErrInsufficientReputation unless the user's current total
+// covers it. The check and the debit are a single conditional update.
+func (s *MongoReputationStore) SpendReputation(ctx context.Context, event *types.ReputationEvent) (*types.ReputationEvent, error) {
+	_, err := withTransaction(ctx, s.client, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		filter := bson.M{"_id": event.UserID, "reputation": bson.M{"$gte": -event.Points}}
+		res, err := s.userColl.UpdateOne(sessCtx, filter, bson.M{"$inc": bson.M{"reputation": event.Points}})
+		if err != nil {
+			return nil, storeError(err)
+		}
+		if res.MatchedCount == 0 {
+			return nil, ErrInsufficientReputation
+		}
+
+		inserted, err := s.coll.InsertOne(sessCtx, event)
+		if err != nil {
+			return nil, storeError(err)
+		}
+		event.ID = inserted.InsertedID.(primitive.ObjectID)
+
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+// ReverseEvents records a reversal for every matching event that hasn't
+// been reversed yet and returns the reversals, all in one transaction. The
+// unique index on reverses fails a concurrent second reversal of the same
+// event with ErrConflict.
+func (s *MongoReputationStore) ReverseEvents(ctx context.Context, filter *ReputationEventFilter) ([]*types.ReputationEvent, error) {
+	reversals, err := withTransaction(ctx, s.client, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return s.reverseEvents(sessCtx, filter)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reversals.([]*types.ReputationEvent), nil
+}
+
+func (s *MongoReputationStore) reverseEvents(ctx context.Context, filter *ReputationEventFilter) ([]*types.ReputationEvent, error) {
+	query := bson.M{"reverses": bson.M{"$exists": false}}
+
+	if filter.ActorID != nil {
+		query["actorID"] = *filter.ActorID
+	}
+	if filter.QuestionID != nil {
+		query["questionID"] = *filter.QuestionID
+	}
+	if filter.AnswerID != nil {
+		query["answerID"] = *filter.AnswerID
+	} else if filter.QuestionOnly {
+		query["answerID"] = bson.M{"$exists": false}
+	}
+	if len(filter.Reasons) > 0 {
+		query["reason"] = bson.M{"$in": filter.Reasons}
+	}
+
+	var events []*types.ReputationEvent
+	cursor, err := s.coll.Find(ctx, query)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, storeError(err)
+	}
+
+	if len(events) == 0 {
+		return []*types.ReputationEvent{}, nil
+	}
+
+	ids := make([]primitive.ObjectID, len(events))
+	for i, event := range events {
+		ids[i] = event.ID
+	}
+
+	var reversed []*types.ReputationEvent
+	cursor, err = s.coll.Find(ctx, bson.M{"reverses": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	if err := cursor.All(ctx, &reversed); err != nil {
+		return nil, storeError(err)
+	}
+
+	isReversed := make(map[primitive.ObjectID]bool, len(reversed))
+	for _, event := range reversed {
+		isReversed[*event.Reverses] = true
+	}
+
+	reversals := []*types.ReputationEvent{}
+	for _, event := range events {
+		if isReversed[event.ID] {
+			continue
+		}
+
+		reversal := *event
+		reversal.ID = primitive.NilObjectID
+		reversal.Points = -event.Points
+		reversal.Reverses = &event.ID
+		reversal.CreatedAt = time.Now().UTC()
+
+		if err := s.recordEvent(ctx, &reversal); err != nil {
+			return nil, err
+		}
+		reversals = append(reversals, &reversal)
+	}
+
+	return reversals, nil
+}
+
+// RecordVote moves the ledger from the voter's previous vote on a post to
+// their new one in one transaction. Votes on your own posts are worth
+// nothing.
+func (s *MongoReputationStore) RecordVote(ctx context.Context, post types.PostRef, voterID primitive.ObjectID, prev, next types.VoteState) error {
+	if prev == next || voterID == post.OwnerID {
+		return nil
+	}
+
+	_, err := withTransaction(ctx, s.client, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, s.recordVote(sessCtx, post, voterID, prev, next)
+	})
+	return err
+}
+
+func (s *MongoReputationStore) recordVote(ctx context.Context, post types.PostRef, voterID primitive.ObjectID, prev, next types.VoteState) error {
+	if prev != types.VoteNone {
+		filter := &ReputationEventFilter{
+			ActorID: &voterID,
+			QuestionID: &post.QuestionID,
+			AnswerID: post.AnswerID,
+			QuestionOnly: post.AnswerID == nil,
+			Reasons: types.VoteReasons(post, prev),
+		}
+		if _, err := s.reverseEvents(ctx, filter); err != nil {
+			return err
+		}
+	}
+
+	for _, event := range types.VoteReputationEvents(post, voterID, next) {
+		if err := s.recordEvent(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *MongoReputationStore) GetReputationHistory(ctx context.Context, userID primitive.ObjectID, params *PageParams) (*types.ReputationHistory, error) {
+	page, limit, skip := pageBounds(params.Page, params.Limit, "")
+	filter := bson.M{"userID": userID}
+
+	total, err := s.coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(skip).
+		SetLimit(limit)
+
+	events := []*types.ReputationEvent{}
+	cursor, err := s.coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, storeError(err)
+	}
+
+	return &types.ReputationHistory{
+		Events: events,
+		Total: total,
+		Page: page,
+		Limit: limit,
+		HasNext: skip+int64(len(events)) < total,
+	}, nil
+}
+
+// RecomputeReputation rebuilds every user's total from the ledger and returns
+// the number of users whose total changed.
+func (s *MongoReputationStore) RecomputeReputation(ctx context.Context) (int64, error) {
+	pipeline := []bson.M{
+		{"$group": bson.M{"_id": "$userID", "total": bson.M{"$sum": "$points"}}},
+	}
+
+	cursor, err := s.coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, storeError(err)
+	}
+
+	var sums []struct {
+		UserID primitive.ObjectID `bson:"_id"`
+		Total int `bson:"total"`
+	}
+	if err := cursor.All(ctx, &sums); err != nil {
+		return 0, storeError(err)
+	}
+
+	totals := make(map[primitive.ObjectID]int, len(sums))
+	for _, sum := range sums {
+		totals[sum.UserID] = sum.Total
+	}
+
+	cursor, err = s.userColl.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"_id": 1, "reputation": 1}))
+	if err != nil {
+		return 0, storeError(err)
+	}
+
+	var users []struct {
+		ID primitive.ObjectID `bson:"_id"`
+		Reputation int `bson:"reputation"`
+	}
+	if err := cursor.All(ctx, &users); err != nil {
+		return 0, storeError(err)
+	}
+
+	models := []mongo.WriteModel{}
+	for _, user := range users {
+		if user.Reputation == totals[user.ID] {
+			continue
+		}
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": user.ID}).
+			SetUpdate(bson.M{"$set": bson.M{"reputation": totals[user.ID]}}))
+	}
+
+	if len(models) == 0 {
+		return 0, nil
+	}
+
+	res, err := s.userColl.BulkWrite(ctx, models)
+	if err != nil {
+		return 0, storeError(err)
+	}
+
+	return res.ModifiedCount, nil
+}
@@ -0,0 +1,142 @@
+package db
+
+import (
+	"context"
+	"os"
+
+	"github.com/fullstack/dev-overflow/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	QUESTIONREVISIONCOLL = "question_revisions"
+	ANSWERREVISIONCOLL = "answer_revisions"
+)
+
+type RevisionStore interface {
+	EnsureIndexes(context.Context) error
+	CreateQuestionRevision(context.Context, *types.QuestionRevision) (*types.QuestionRevision, error)
+	GetQuestionRevisions(context.Context, primitive.ObjectID) ([]*types.QuestionRevision, error)
+	GetQuestionRevision(context.Context, primitive.ObjectID, int) (*types.QuestionRevision, error)
+	CreateAnswerRevision(context.Context, *types.AnswerRevision) (*types.AnswerRevision, error)
+	GetAnswerRevisions(context.Context, primitive.ObjectID) ([]*types.AnswerRevision, error)
+	GetAnswerRevision(context.Context, primitive.ObjectID, int) (*types.AnswerRevision, error)
+}
+
+type MongoRevisionStore struct {
+	client *mongo.Client
+	questionColl *mongo.Collection
+	answerColl *mongo.Collection
+}
+
+func NewMongoRevisionStore(client *mongo.Client) *MongoRevisionStore {
+	var mongoenvdbname = os.Getenv("MONGO_DB_NAME")
+	return &MongoRevisionStore{
+		client: client,
+		questionColl: client.Database(mongoenvdbname).Collection(QUESTIONREVISIONCOLL),
+		answerColl: client.Database(mongoenvdbname).Collection(ANSWERREVISIONCOLL),
+	}
+}
+
+// EnsureIndexes creates the unique (post, revision) indexes, so two
+// concurrent edits can't record the same revision number.
+func (s *MongoRevisionStore) EnsureIndexes(ctx context.Context) error {
+	questionIndex := mongo.IndexModel{
+		Keys: bson.D{{Key: "questionID", Value: 1}, {Key: "revision", Value: 1}},
+		Options: options.Index().SetName("question_revision").SetUnique(true),
+	}
+
+	if _, err := s.questionColl.Indexes().CreateOne(ctx, questionIndex); err != nil {
+		return storeError(err)
+	}
+
+	answerIndex := mongo.IndexModel{
+		Keys: bson.D{{Key: "answerID", Value: 1}, {Key: "revision", Value: 1}},
+		Options: options.Index().SetName("answer_revision").SetUnique(true),
+	}
+
+	if _, err := s.answerColl.Indexes().CreateOne(ctx, answerIndex); err != nil {
+		return storeError(err)
+	}
+
+	return nil
+}
+
+func (s *MongoRevisionStore) CreateQuestionRevision(ctx context.Context, revision *types.QuestionRevision) (*types.QuestionRevision, error) {
+	res, err := s.questionColl.InsertOne(ctx, revision)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	revision.ID = res.InsertedID.(primitive.ObjectID)
+
+	return revision, nil
+}
+
+func (s *MongoRevisionStore) GetQuestionRevisions(ctx context.Context, questionID primitive.ObjectID) ([]*types.QuestionRevision, error) {
+	revisions := []*types.QuestionRevision{}
+
+	opts := options.Find().SetSort(bson.M{"revision": 1})
+	cursor, err := s.questionColl.Find(ctx, bson.M{"questionID": questionID}, opts)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return nil, storeError(err)
+	}
+
+	return revisions, nil
+}
+
+func (s *MongoRevisionStore) GetQuestionRevision(ctx context.Context, questionID primitive.ObjectID, revision int) (*types.QuestionRevision, error) {
+	var questionRevision types.QuestionRevision
+
+	filter := bson.M{"questionID": questionID, "revision": revision}
+	if err := s.questionColl.FindOne(ctx, filter).Decode(&questionRevision); err != nil {
+		return nil, storeError(err)
+	}
+
+	return &questionRevision, nil
+}
+
+func (s *MongoRevisionStore) CreateAnswerRevision(ctx context.Context, revision *types.AnswerRevision) (*types.AnswerRevision, error) {
+	res, err := s.answerColl.InsertOne(ctx, revision)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	revision.ID = res.InsertedID.(primitive.ObjectID)
+
+	return revision, nil
+}
+
+func (s *MongoRevisionStore) GetAnswerRevisions(ctx context.Context, answerID primitive.ObjectID) ([]*types.AnswerRevision, error) {
+	revisions := []*types.AnswerRevision{}
+
+	opts := options.Find().SetSort(bson.M{"revision": 1})
+	cursor, err := s.answerColl.Find(ctx, bson.M{"answerID": answerID}, opts)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return nil, storeError(err)
+	}
+
+	return revisions, nil
+}
+
+func (s *MongoRevisionStore) GetAnswerRevision(ctx context.Context, answerID primitive.ObjectID, revision int) (*types.AnswerRevision, error) {
+	var answerRevision types.AnswerRevision
+
+	filter := bson.M{"answerID": answerID, "revision": revision}
+	if err := s.answerColl.FindOne(ctx, filter).Decode(&answerRevision); err != nil {
+		return nil, storeError(err)
+	}
+
+	return &answerRevision, nil
+}
@@ -0,0 +1,434 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fullstack/dev-overflow/types"
+	"github.com/fullstack/dev-overflow/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	searchSnippetWidth = 160
+	similarCandidateLimit = 50
+	similarDescriptionTerms = 40
+	// maxSearchResults is how deep search pages may go. Results from every
+	// collection are merged in memory, so each one is fetched up to the end
+	// of the requested page.
+	maxSearchResults = 1000
+)
+
+var ErrInvalidSearchType = errors.New("invalid search type")
+
+type SearchStore interface {
+	EnsureIndexes(context.Context) error
+	Search(context.Context, *SearchParams) (*types.SearchResults, error)
+	SimilarQuestions(context.Context, *types.QuestionDraft, int) ([]*types.SimilarQuestion, error)
+}
+
+type MongoSearchStore struct {
+	client *mongo.Client
+	database *mongo.Database
+	UserStore
+	TagStore
+}
+
+func NewMongoSearchStore(client *mongo.Client, userStore UserStore, tagStore TagStore) *MongoSearchStore {
+	var mongoenvdbname = os.Getenv("MONGO_DB_NAME")
+	return &MongoSearchStore{
+		client: client,
+		database: client.Database(mongoenvdbname),
+		UserStore: userStore,
+		TagStore: tagStore,
+	}
+}
+
+// EnsureIndexes creates the text indexes search relies on. A collection can
+// only have one text index, so these are the only ones.
+func (s *MongoSearchStore) EnsureIndexes(ctx context.Context) error {
+	indexes := map[string]mongo.IndexModel{
+		QUESTIONCOLL: {
+			Keys: bson.D{{Key: "title", Value: "text"}, {Key: "description", Value: "text"}},
+			Options: options.Index().SetName("question_text").SetWeights(bson.M{"title": 3, "description": 1}),
+		},
+		ANSWERCOLL: {
+			Keys: bson.D{{Key: "content", Value: "text"}},
+			Options: options.Index().SetName("answer_text"),
+		},
+		TAGCOLL: {
+			Keys: bson.D{{Key: "name", Value: "text"}, {Key: "description", Value: "text"}},
+			Options: options.Index().SetName("tag_text").SetWeights(bson.M{"name": 5, "description": 1}),
+		},
+		USERCOLL: {
+			Keys: bson.D{{Key: "firstName", Value: "text"}, {Key: "lastName", Value: "text"}},
+			Options: options.Index().SetName("user_text"),
+		},
+	}
+
+	for coll, index := range indexes {
+		if _, err := s.database.Collection(coll).Indexes().CreateOne(ctx, index); err != nil {
+			return storeError(err)
+		}
+	}
+
+	return nil
+}
+
+func (s *MongoSearchStore) Search(ctx context.Context, params *SearchParams) (*types.SearchResults, error) {
+	var (
+		query = types.ParseSearchQuery(params.Query)
+		page, limit, skip = pageBounds(params.Page, params.Limit, "")
+		fetch = skip + limit
+	)
+
+	if fetch > maxSearchResults {
+		fetch = maxSearchResults
+	}
+
+	searches := map[string]func(context.Context, *types.SearchQuery, int64) ([]*types.SearchResult, int64, error){
+		types.SearchTypeQuestion: s.searchQuestions,
+		types.SearchTypeAnswer: s.searchAnswers,
+		types.SearchTypeUser: s.searchUsers,
+		types.SearchTypeTag: s.searchTags,
+	}
+
+	if params.Type != "" {
+		search, ok := searches[params.Type]
+		if !ok {
+			return nil, ErrInvalidSearchType
+		}
+		searches = map[string]func(context.Context, *types.SearchQuery, int64) ([]*types.SearchResult, int64, error){
+			params.Type: search,
+		}
+	}
+
+	// Users and tags have no author or score, so post operators rule them
+	// out. Tags and the answered state only exist on questions.
+	if query.HasPostFilters() {
+		delete(searches, types.SearchTypeUser)
+		delete(searches, types.SearchTypeTag)
+	}
+	if query.HasQuestionFilters() {
+		delete(searches, types.SearchTypeAnswer)
+	}
+
+	results := &types.SearchResults{
+		Query: params.Query,
+		Results: []*types.SearchResult{},
+		Page: page,
+		Limit: limit,
+	}
+
+	if query.Text == "" && !query.HasPostFilters() {
+		return results, nil
+	}
+
+	all := []*types.SearchResult{}
+	for _, search := range searches {
+		found, total, err := search(ctx, query, fetch)
+		if err != nil {
+			return nil, storeError(err)
+		}
+		all = append(all, found...)
+		results.Total += total
+	}
+
+	sort.SliceStable(all, func(i, j int) bool {
+		if all[i].Relevance != all[j].Relevance {
+			return all[i].Relevance > all[j].Relevance
+		}
+		return all[i].CreatedAt.After(all[j].CreatedAt)
+	})
+
+	if skip < int64(len(all)) && skip < fetch {
+		end := skip + limit
+		if end > fetch {
+			end = fetch
+		}
+		if end > int64(len(all)) {
+			end = int64(len(all))
+		}
+		results.Results = all[skip:end]
+	}
+
+	results.HasNext = skip+int64(len(results.Results)) < results.Total && skip+limit < maxSearchResults
+
+	return results, nil
+}
+
+// SimilarQuestions returns up to limit existing questions that look like
+// the draft, best match first. Candidates come from the text index and are
+// re-ranked with types.RankSimilarQuestion. Questions closed as duplicates
+// are left out, their original is suggested instead.
+func (s *MongoSearchStore) SimilarQuestions(ctx context.Context, draft *types.QuestionDraft, limit int) ([]*types.SimilarQuestion, error) {
+	tagIDs := []primitive.ObjectID{}
+	for _, name := range draft.Tags {
+		tag, err := s.TagStore.GetTagByName(ctx, utils.FormatTag(name))
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		tagIDs = append(tagIDs, tag.ID)
+	}
+
+	terms := utils.Tokenize(draft.Description)
+	if len(terms) > similarDescriptionTerms {
+		terms = terms[:similarDescriptionTerms]
+	}
+	terms = append(utils.Tokenize(draft.Title), terms...)
+
+	filter := bson.M{
+		"$text": bson.M{"$search": strings.Join(terms, " ")},
+		"duplicateOf": bson.M{"$exists": false},
+	}
+	opts := options.Find().
+		SetLimit(similarCandidateLimit).
+		SetProjection(bson.M{"relevance": bson.M{"$meta": "textScore"}}).
+		SetSort(bson.D{{Key: "relevance", Value: bson.M{"$meta": "textScore"}}})
+
+	cursor, err := s.database.Collection(QUESTIONCOLL).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	var candidates []*types.Question
+	if err := cursor.All(ctx, &candidates); err != nil {
+		return nil, storeError(err)
+	}
+
+	similar := []*types.SimilarQuestion{}
+	for _, question := range candidates {
+		if ranked := types.RankSimilarQuestion(draft, tagIDs, question); ranked != nil {
+			similar = append(similar, ranked)
+		}
+	}
+
+	sort.SliceStable(similar, func(i, j int) bool {
+		return similar[i].Score > similar[j].Score
+	})
+
+	if len(similar) > limit {
+		similar = similar[:limit]
+	}
+
+	return similar, nil
+}
+
+func (s *MongoSearchStore) searchQuestions(ctx context.Context, query *types.SearchQuery, limit int64) ([]*types.SearchResult, int64, error) {
+	conditions := []bson.M{}
+
+	if len(query.Tags) > 0 {
+		tagIDs := make([]primitive.ObjectID, len(query.Tags))
+		for i, name := range query.Tags {
+			tag, err := s.TagStore.GetTagByName(ctx, utils.FormatTag(name))
+			if err != nil {
+				if errors.Is(err, ErrNotFound) {
+					return nil, 0, nil
+				}
+				return nil, 0, err
+			}
+			tagIDs[i] = tag.ID
+		}
+		conditions = append(conditions, bson.M{"tags": bson.M{"$all": tagIDs}})
+	}
+
+	if query.IsAnswered != nil {
+		if *query.IsAnswered {
+			conditions = append(conditions, bson.M{"answerCount": bson.M{"$gt": 0}})
+		} else {
+			conditions = append(conditions, bson.M{"answerCount": 0})
+		}
+	}
+
+	postConditions, ok, err := s.postConditions(ctx, query)
+	if err != nil || !ok {
+		return nil, 0, err
+	}
+
+	var docs []struct {
+		types.Question `bson:",inline"`
+		Relevance float64 `bson:"relevance"`
+	}
+
+	total, err := s.find(ctx, QUESTIONCOLL, query, append(conditions, postConditions...), "createdAt", limit, &docs)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	results := make([]*types.SearchResult, len(docs))
+	for i, doc := range docs {
+		results[i] = &types.SearchResult{
+			Type: types.SearchTypeQuestion,
+			ID: doc.ID,
+			Title: doc.Title,
+			Snippet: utils.Highlight(doc.Title+"\n"+doc.Description, query.Terms, searchSnippetWidth),
+			Relevance: doc.Relevance,
+			CreatedAt: doc.CreatedAt,
+		}
+	}
+
+	return results, total, nil
+}
+
+func (s *MongoSearchStore) searchAnswers(ctx context.Context, query *types.SearchQuery, limit int64) ([]*types.SearchResult, int64, error) {
+	conditions, ok, err := s.postConditions(ctx, query)
+	if err != nil || !ok {
+		return nil, 0, err
+	}
+
+	var docs []struct {
+		types.Answer `bson:",inline"`
+		Relevance float64 `bson:"relevance"`
+	}
+
+	total, err := s.find(ctx, ANSWERCOLL, query, conditions, "createdAt", limit, &docs)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	results := make([]*types.SearchResult, len(docs))
+	for i, doc := range docs {
+		questionID := doc.QuestionID
+		results[i] = &types.SearchResult{
+			Type: types.SearchTypeAnswer,
+			ID: doc.ID,
+			Snippet: utils.Highlight(doc.Description, query.Terms, searchSnippetWidth),
+			QuestionID: &questionID,
+			Relevance: doc.Relevance,
+			CreatedAt: doc.CreatedAt,
+		}
+	}
+
+	return results, total, nil
+}
+
+func (s *MongoSearchStore) searchUsers(ctx context.Context, query *types.SearchQuery, limit int64) ([]*types.SearchResult, int64, error) {
+	var docs []struct {
+		types.User `bson:",inline"`
+		Relevance float64 `bson:"relevance"`
+	}
+
+	total, err := s.find(ctx, USERCOLL, query, nil, "joinedAt", limit, &docs)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	results := make([]*types.SearchResult, len(docs))
+	for i, doc := range docs {
+		results[i] = &types.SearchResult{
+			Type: types.SearchTypeUser,
+			ID: doc.ID,
+			Title: doc.DisplayName(),
+			Snippet: utils.Highlight(doc.DisplayName(), query.Terms, searchSnippetWidth),
+			Relevance: doc.Relevance,
+			CreatedAt: doc.JoinedAt,
+		}
+	}
+
+	return results, total, nil
+}
+
+func (s *MongoSearchStore) searchTags(ctx context.Context, query *types.SearchQuery, limit int64) ([]*types.SearchResult, int64, error) {
+	var docs []struct {
+		types.Tag `bson:",inline"`
+		Relevance float64 `bson:"relevance"`
+	}
+
+	total, err := s.find(ctx, TAGCOLL, query, nil, "createdAt", limit, &docs)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	results := make([]*types.SearchResult, len(docs))
+	for i, doc := range docs {
+		results[i] = &types.SearchResult{
+			Type: types.SearchTypeTag,
+			ID: doc.ID,
+			Title: doc.Name,
+			Snippet: utils.Highlight(doc.Name+"\n"+doc.Description, query.Terms, searchSnippetWidth),
+			Relevance: doc.Relevance,
+			CreatedAt: doc.CreatedAt,
+		}
+	}
+
+	return results, total, nil
+}
+
+// postConditions translates the user and score operators. ok is false when
+// the query can't match any post, e.g. for an unknown user.
+func (s *MongoSearchStore) postConditions(ctx context.Context, query *types.SearchQuery) ([]bson.M, bool, error) {
+	conditions := []bson.M{}
+
+	if query.UserID != "" {
+		userID, err := objectID(query.UserID)
+		if err != nil {
+			user, err := s.UserStore.GetUserByID(ctx, query.UserID)
+			if err != nil {
+				if errors.Is(err, ErrNotFound) {
+					return nil, false, nil
+				}
+				return nil, false, err
+			}
+			userID = user.ID
+		}
+		conditions = append(conditions, bson.M{"userID": userID})
+	}
+
+	if query.MinScore != nil {
+		conditions = append(conditions, bson.M{"score": bson.M{"$gte": *query.MinScore}})
+	}
+
+	if query.MaxScore != nil {
+		conditions = append(conditions, bson.M{"score": bson.M{"$lte": *query.MaxScore}})
+	}
+
+	return conditions, true, nil
+}
+
+// find runs a ranked text search over coll, or a recency ordered query when
+// the search has no free text, decoding up to limit documents into docs.
+func (s *MongoSearchStore) find(ctx context.Context, coll string, query *types.SearchQuery, conditions []bson.M, dateField string, limit int64, docs interface{}) (int64, error) {
+	if query.Text != "" {
+		conditions = append(conditions, bson.M{"$text": bson.M{"$search": query.Text}})
+	}
+
+	filter := bson.M{}
+	if len(conditions) > 0 {
+		filter["$and"] = conditions
+	}
+
+	opts := options.Find().SetLimit(limit)
+	if query.Text != "" {
+		opts.SetProjection(bson.M{"relevance": bson.M{"$meta": "textScore"}})
+		opts.SetSort(bson.D{{Key: "relevance", Value: bson.M{"$meta": "textScore"}}})
+	} else {
+		opts.SetSort(bson.D{{Key: dateField, Value: -1}})
+	}
+
+	collection := s.database.Collection(coll)
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, storeError(err)
+	}
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return 0, storeError(err)
+	}
+
+	if err := cursor.All(ctx, docs); err != nil {
+		return 0, storeError(err)
+	}
+
+	return total, nil
+}
@@ -2,7 +2,6 @@ package db
 
 import (
 	"context"
-	"errors"
 	"os"
 
 	"github.com/fullstack/dev-overflow/types"
@@ -10,10 +9,15 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 const TAGCOLL = "tags"
 
+// ErrTagExists is returned when creating or renaming a tag onto the name of
+// another tag.
+var ErrTagExists = newStoreError(ErrConflict, "a tag with this name already exists")
+
 type MongoTagStore struct {
 	client *mongo.Client
 	collection *mongo.Collection
@@ -28,25 +32,42 @@ func NewMongoTagStore(client *mongo.Client) *MongoTagStore {
 }
 
 type TagStore interface {
+	EnsureIndexes(context.Context) error
 	CreateTag(context.Context, *types.Tag) (*types.Tag, error)
 	GetTagByID(context.Context, string) (*types.Tag, error)
 	GetTagByName(context.Context, string) (*types.Tag, error)
 	GetTags(context.Context) ([]*types.Tag, error)
-	UpdateTag(context.Context, Map, *types.UpdateTagQuestionAndFollowers) error
+	UpdateTag(context.Context, Map, *types.UpdateTagFollowers) error
+	EditTag(context.Context, string, *types.EditTagParams) (*types.Tag, error)
 	UpdateManyFollowersByID(context.Context, primitive.ObjectID) error
-	UpdateManyQuestionsByID(context.Context, primitive.ObjectID) error
+	CountTagQuestions(context.Context, []primitive.ObjectID) error
+}
+
+// EnsureIndexes creates the unique name index, so no two tags can share a
+// name.
+func (s *MongoTagStore) EnsureIndexes(ctx context.Context) error {
+	index := mongo.IndexModel{
+		Keys: bson.D{{Key: "name", Value: 1}},
+		Options: options.Index().SetName("tag_name").SetUnique(true),
+	}
+
+	if _, err := s.collection.Indexes().CreateOne(ctx, index); err != nil {
+		return storeError(err)
+	}
+
+	return nil
 }
 
 func (s *MongoTagStore) GetTagByID(ctx context.Context, id string) (*types.Tag, error) {
 
-	oid, err := primitive.ObjectIDFromHex(id)
+	oid, err := objectID(id)
 	if err != nil {
 		return nil, err
 	}
 
 	var tag types.Tag
 	if err := s.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&tag); err != nil {
-		return nil, err
+		return nil, storeError(err)
 	}
 
 	return &tag, nil
@@ -56,7 +77,7 @@ func (s *MongoTagStore) GetTagByName(ctx context.Context, name string) (*types.T
 	var tag types.Tag
 	name = utils.FormatTag(name)
 	if err := s.collection.FindOne(ctx, bson.M{"name": name}).Decode(&tag); err != nil {
-		return nil, err
+		return nil, storeError(err)
 	}
 
 	return &tag, nil
@@ -66,11 +87,11 @@ func (s *MongoTagStore) GetTags(ctx context.Context) ([]*types.Tag, error) {
 	var tags []*types.Tag
 	cursor, err := s.collection.Find(ctx, bson.M{})
 	if err != nil {
-		return nil, err
+		return nil, storeError(err)
 	}
 
 	if err := cursor.All(ctx, &tags); err != nil {
-		return nil, err
+		return nil, storeError(err)
 	}
 
 	return tags, nil
@@ -81,7 +102,10 @@ func (s *MongoTagStore) CreateTag(c context.Context, tag *types.Tag) (*types.Tag
 	tag.Name = utils.FormatTag(tag.Name)
 	res, err := s.collection.InsertOne(c, tag)
 	if err != nil {
-		return nil , err
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrTagExists
+		}
+		return nil , storeError(err)
 	}
 	
 	tag.ID = res.InsertedID.(primitive.ObjectID)
@@ -89,44 +113,88 @@ func (s *MongoTagStore) CreateTag(c context.Context, tag *types.Tag) (*types.Tag
 	return tag, nil
 }
 
-func (s *MongoTagStore) UpdateTag(ctx context.Context, filter Map, update *types.UpdateTagQuestionAndFollowers) error {
+func (s *MongoTagStore) UpdateTag(ctx context.Context, filter Map, update *types.UpdateTagFollowers) error {
 
 	oid, ok := filter["_id"]
 	if !ok {
-		return errors.New("filter[_id] is not a primitive.ObjectID")
+		return newStoreError(ErrInvalidID, "filter[_id] is not a primitive.ObjectID")
 	}
 
-	filter["_id"] = oid
+	if update.Followers.IsZero() {
+		return nil
+	}
 
+	// The follower is added, and the counter bumped, only when it is not in
+	// the tag yet.
 	updateDoc := bson.M{
-		"$push": bson.M{
-			"questions": bson.M{"$each":[]primitive.ObjectID{update.Questions}},
-			"followers": bson.M{"$each":[]primitive.ObjectID{update.Followers}},
-		},
+		"$push": bson.M{"followers": update.Followers},
+		"$inc": bson.M{"followerCount": 1},
 	}
 
-	_, err := s.collection.UpdateOne(ctx, filter, updateDoc)
+	res, err := s.collection.UpdateOne(ctx, bson.M{"_id": oid, "followers": bson.M{"$ne": update.Followers}}, updateDoc)
 	if err != nil {
-		return err
+		return storeError(err)
+	}
+
+	if res.MatchedCount == 0 {
+		n, err := s.collection.CountDocuments(ctx, bson.M{"_id": oid})
+		if err != nil {
+			return storeError(err)
+		}
+		if n == 0 {
+			return ErrNotFound
+		}
 	}
 
 	return nil
 }
 
+func (s *MongoTagStore) EditTag(ctx context.Context, id string, params *types.EditTagParams) (*types.Tag, error) {
+	oid, err := objectID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	set := bson.M{"name": utils.FormatTag(params.Name), "description": params.Description}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var tag types.Tag
+	if err := s.collection.FindOneAndUpdate(ctx, bson.M{"_id": oid}, bson.M{"$set": set}, opts).Decode(&tag); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrTagExists
+		}
+		return nil, storeError(err)
+	}
+
+	return &tag, nil
+}
+
 func (s *MongoTagStore) UpdateManyFollowersByID(ctx context.Context, id primitive.ObjectID) error {
-	_, err := s.collection.UpdateMany(ctx, bson.M{"followers": id}, bson.M{"$pull": bson.M{"followers": id}})
+	_, err := s.collection.UpdateMany(ctx, bson.M{"followers": id}, bson.M{"$pull": bson.M{"followers": id}, "$inc": bson.M{"followerCount": -1}})
 	if err != nil {
-		return err
+		return storeError(err)
 	}
 
 	return nil
 }
 
-func (s *MongoTagStore) UpdateManyQuestionsByID(ctx context.Context, id primitive.ObjectID) error {
-	_, err := s.collection.UpdateMany(ctx, bson.M{"questions": id}, bson.M{"$pull": bson.M{"questions": id}})
-	if err != nil {
-		return err
+// CountTagQuestions sets the question count of each tag to the number of
+// questions tagged with it. Questions own their tags, so recounting is
+// idempotent however often a change is applied.
+func (s *MongoTagStore) CountTagQuestions(ctx context.Context, tagIDs []primitive.ObjectID) error {
+	questions := s.collection.Database().Collection(QUESTIONCOLL)
+
+	for _, tagID := range tagIDs {
+		n, err := questions.CountDocuments(ctx, bson.M{"tags": tagID})
+		if err != nil {
+			return storeError(err)
+		}
+
+		if _, err := s.collection.UpdateOne(ctx, bson.M{"_id": tagID}, bson.M{"$set": bson.M{"questionCount": n}}); err != nil {
+			return storeError(err)
+		}
 	}
 
 	return nil
-}
\ No newline at end of file
+}
@@ -27,20 +27,37 @@ func NewMongoUserStore(client *mongo.Client) *MongoUserStore {
 }
 
 type UserStore interface {
+	EnsureIndexes(context.Context) error
 	CreateUser(context.Context, *types.User) (*types.User, error)
 	GetUserByID(context.Context, string) (*types.User, error)
 	GetUsers(context.Context, UserQueryParams) ([]*types.User, error)
 	SaveQuestion(context.Context, *types.SaveQuestionParam) (bool  ,error)
-	UpdateUserQuestionsField(context.Context, primitive.ObjectID, primitive.ObjectID) error
-	UpdateUserAnswersField(context.Context, primitive.ObjectID, primitive.ObjectID) error
-	UpdateUser(context.Context, string, *types.UpdateUserParam) error
+	IncrementQuestionCount(context.Context, primitive.ObjectID, int) error
+	IncrementAnswerCount(context.Context, primitive.ObjectID, int) error
+	UpdateUser(context.Context, string, types.UserUpdate) (*types.User, error)
+	UpdateUserRole(context.Context, string, types.Role) error
 	DeleteUser(context.Context, string) error
 }
 
+// EnsureIndexes creates the unique clerkID index, so replayed or concurrent
+// Clerk webhooks can't create the same user twice.
+func (s *MongoUserStore) EnsureIndexes(ctx context.Context) error {
+	index := mongo.IndexModel{
+		Keys: bson.D{{Key: "clerkID", Value: 1}},
+		Options: options.Index().SetName("user_clerk_id").SetUnique(true),
+	}
+
+	if _, err := s.coll.Indexes().CreateOne(ctx, index); err != nil {
+		return storeError(err)
+	}
+
+	return nil
+}
+
 func (s *MongoUserStore) CreateUser(c context.Context, user *types.User) (*types.User, error) {
 	res, err := s.coll.InsertOne(c, user)
 	if err != nil {
-		return nil , err
+		return nil , storeError(err)
 	}
 	
 	user.ID = res.InsertedID.(primitive.ObjectID)
@@ -51,7 +68,7 @@ func (s *MongoUserStore) CreateUser(c context.Context, user *types.User) (*types
 func (s *MongoUserStore) GetUserByID(ctx context.Context, id string) (*types.User, error) {
 	var user types.User
 	if err := s.coll.FindOne(ctx, bson.M{"clerkID": id}).Decode(&user); err != nil {
-		return nil, err
+		return nil, storeError(err)
 	}
 
 	return &user, nil
@@ -85,54 +102,65 @@ func (s *MongoUserStore) GetUsers(ctx context.Context, params UserQueryParams) (
 
 	cursor, err := s.coll.Find(ctx, query, &opt)
 	if err != nil {
-		return nil, err
+		return nil, storeError(err)
 	}
 
 	if err := cursor.All(ctx, &users); err != nil {
-		return nil, err
+		return nil, storeError(err)
 	}
 
 	return users, nil
 }
 
-func (s *MongoUserStore) UpdateUser(ctx context.Context, clerkID string, update *types.UpdateUserParam) error {
+func (s *MongoUserStore) UpdateUser(ctx context.Context, clerkID string, update types.UserUpdate) (*types.User, error) {
+	fields := update.Fields()
+	if len(fields) == 0 {
+		return s.GetUserByID(ctx, clerkID)
+	}
 
 	filter := bson.M{"clerkID": clerkID}
-	updateData := bson.M{"$set": update.UpdateData}
+	updateData := bson.M{"$set": fields}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
 
-	result := s.coll.FindOneAndUpdate(ctx, filter, updateData)
+	result := s.coll.FindOneAndUpdate(ctx, filter, updateData, opts)
 
 	var updatedUser types.User
 	if err := result.Decode(&updatedUser); err != nil {
-		return err
+		return nil, storeError(err)
 	}
 
-	return nil
+	return &updatedUser, nil
 }
 
-func (s *MongoUserStore) UpdateUserQuestionsField(ctx context.Context, userID primitive.ObjectID, questionID primitive.ObjectID) error {
-	filter := bson.M{"_id": userID}
-	updateData := bson.M{
-		"$push": bson.M{
-			"questions": bson.M{"$each":[]primitive.ObjectID{questionID}},
-		},
-	}
+// IncrementQuestionCount adds n to the user's question count.
+func (s *MongoUserStore) IncrementQuestionCount(ctx context.Context, userID primitive.ObjectID, n int) error {
+	return s.incrementCount(ctx, userID, "questionCount", n)
+}
 
-	result := s.coll.FindOneAndUpdate(ctx, filter, updateData)
+// IncrementAnswerCount adds n to the user's answer count.
+func (s *MongoUserStore) IncrementAnswerCount(ctx context.Context, userID primitive.ObjectID, n int) error {
+	return s.incrementCount(ctx, userID, "answerCount", n)
+}
 
-	var updatedUser types.User
-	if err := result.Decode(&updatedUser); err != nil {
-		return err
+func (s *MongoUserStore) incrementCount(ctx context.Context, userID primitive.ObjectID, field string, n int) error {
+	res, err := s.coll.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{"$inc": bson.M{field: n}})
+	if err != nil {
+		return storeError(err)
+	}
+
+	if res.MatchedCount == 0 {
+		return ErrNotFound
 	}
 
 	return nil
 }
 
-func (s *MongoUserStore) UpdateUserAnswersField(ctx context.Context, userID primitive.ObjectID, answerID primitive.ObjectID) error {
-	filter := bson.M{"_id": userID}
+func (s *MongoUserStore) UpdateUserRole(ctx context.Context, clerkID string, role types.Role) error {
+	filter := bson.M{"clerkID": clerkID}
 	updateData := bson.M{
-		"$push": bson.M{
-			"answers": bson.M{"$each":[]primitive.ObjectID{answerID}},
+		"$set": bson.M{
+			"role": role,
+			"isAdmin": role == types.RoleAdmin,
 		},
 	}
 
@@ -140,7 +168,7 @@ func (s *MongoUserStore) UpdateUserAnswersField(ctx context.Context, userID prim
 
 	var updatedUser types.User
 	if err := result.Decode(&updatedUser); err != nil {
-		return err
+		return storeError(err)
 	}
 
 	return nil
@@ -154,7 +182,7 @@ func (s *MongoUserStore) DeleteUser(ctx context.Context, clerkID string) error {
 
 	_, err = s.coll.DeleteOne(ctx, bson.M{"_id": user.ID})
 	if err != nil {
-		return err
+		return storeError(err)
 	}
 	
 	return nil
@@ -169,7 +197,7 @@ func (s *MongoUserStore) SaveQuestion(ctx context.Context, params *types.SaveQue
 		return false, err
 	}
 
-	paramsOID, err := primitive.ObjectIDFromHex(params.QuestionID)
+	paramsOID, err := objectID(params.QuestionID)
 	if err != nil {
 		return false, err
 	}
@@ -179,7 +207,7 @@ func (s *MongoUserStore) SaveQuestion(ctx context.Context, params *types.SaveQue
 			updateData := bson.M{"$pull": bson.M{"saved": paramsOID}}
 			result := s.coll.FindOneAndUpdate(ctx, bson.M{"clerkID": params.UserID}, updateData)
 			if err := result.Decode(&updatedUser); err != nil {
-				return false, err
+				return false, storeError(err)
 			}
 			return false, nil
 		}
@@ -189,7 +217,7 @@ func (s *MongoUserStore) SaveQuestion(ctx context.Context, params *types.SaveQue
 	updateData := bson.M{"$push": bson.M{"saved": paramsOID}}
 	result := s.coll.FindOneAndUpdate(ctx, filter, updateData)
 	if err := result.Decode(&updatedUser); err != nil {
-		return false, err
+		return false, storeError(err)
 	}
 
 	return true, nil
@@ -0,0 +1,274 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/fullstack/dev-overflow/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const VOTEAUDITCOLL = "vote_audits"
+
+// ErrAuditReviewed is returned when reviewing an audit a moderator already
+// reviewed.
+var ErrAuditReviewed = newStoreError(ErrConflict, "vote audit was already reviewed")
+
+type VoteAuditStore interface {
+	CreateAudit(context.Context, *types.VoteAudit) (*types.VoteAudit, error)
+	GetAuditByID(context.Context, string) (*types.VoteAudit, error)
+	GetAudits(context.Context, *VoteAuditQueryParams) (*types.VoteAuditList, error)
+	ReviewAudit(context.Context, primitive.ObjectID, string, primitive.ObjectID, string) (*types.VoteAudit, error)
+	ReverseVotes(context.Context, *types.VoteAudit) (*types.VoteAudit, error)
+	RestoreVotes(context.Context, *types.VoteAudit) (*types.VoteAudit, error)
+}
+
+type MongoVoteAuditStore struct {
+	client *mongo.Client
+	coll *mongo.Collection
+	VoteStore
+}
+
+func NewMongoVoteAuditStore(client *mongo.Client, voteStore VoteStore) *MongoVoteAuditStore {
+	var mongoenvdbname = os.Getenv("MONGO_DB_NAME")
+	return &MongoVoteAuditStore{
+		client: client,
+		coll: client.Database(mongoenvdbname).Collection(VOTEAUDITCOLL),
+		VoteStore: voteStore,
+	}
+}
+
+// CreateAudit records an audit before any of its votes is reversed, with
+// every vote pending, so ReverseVotes can pick up where a failed run
+// stopped.
+func (s *MongoVoteAuditStore) CreateAudit(ctx context.Context, audit *types.VoteAudit) (*types.VoteAudit, error) {
+	audit.Status = types.AuditPending
+	audit.Pending = make([]primitive.ObjectID, len(audit.Votes))
+	for i, vote := range audit.Votes {
+		audit.Pending[i] = vote.ID
+	}
+
+	res, err := s.coll.InsertOne(ctx, audit)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	audit.ID = res.InsertedID.(primitive.ObjectID)
+
+	return audit, nil
+}
+
+func (s *MongoVoteAuditStore) GetAuditByID(ctx context.Context, id string) (*types.VoteAudit, error) {
+	oid, err := objectID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var audit types.VoteAudit
+	if err := s.coll.FindOne(ctx, bson.M{"_id": oid}).Decode(&audit); err != nil {
+		return nil, storeError(err)
+	}
+
+	return &audit, nil
+}
+
+// GetAudits returns one page of audits, newest first, optionally only those
+// with the given status.
+func (s *MongoVoteAuditStore) GetAudits(ctx context.Context, params *VoteAuditQueryParams) (*types.VoteAuditList, error) {
+	page, limit, skip := pageBounds(params.Page, params.Limit, "")
+
+	filter := bson.M{}
+	if params.Status != "" {
+		filter["status"] = params.Status
+	}
+
+	total, err := s.coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "detectedAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(skip).
+		SetLimit(limit)
+
+	audits := []*types.VoteAudit{}
+	cursor, err := s.coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	if err := cursor.All(ctx, &audits); err != nil {
+		return nil, storeError(err)
+	}
+
+	return &types.VoteAuditList{
+		Audits: audits,
+		Total: total,
+		Page: page,
+		Limit: limit,
+		HasNext: skip+int64(len(audits)) < total,
+	}, nil
+}
+
+// ReviewAudit records a moderator's decision on an audit still awaiting
+// review and returns the reviewed audit. Only one review wins, the others
+// fail with ErrAuditReviewed. Moving to AuditRestoring marks every vote
+// pending for RestoreVotes.
+func (s *MongoVoteAuditStore) ReviewAudit(ctx context.Context, id primitive.ObjectID, status string, reviewerID primitive.ObjectID, note string) (*types.VoteAudit, error) {
+	set := bson.M{
+		"status": status,
+		"reviewedBy": reviewerID,
+		"reviewedAt": time.Now().UTC(),
+		"reviewNote": bson.M{"$literal": note},
+		"pending": []primitive.ObjectID{},
+	}
+	if status == types.AuditRestoring {
+		set["pending"] = "$votes._id"
+	}
+
+	filter := bson.M{"_id": id, "status": types.AuditReversed}
+	update := []bson.M{{"$set": set}}
+
+	var audit types.VoteAudit
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if err := s.coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&audit); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrAuditReviewed
+		}
+		return nil, storeError(err)
+	}
+
+	return &audit, nil
+}
+
+// ReverseVotes clears each pending vote of a pending audit the way its voter
+// would and takes back the reputation it earned, every vote in its own
+// transaction that also takes it off the pending list. Votes changed or
+// removed since detection are dropped from the audit. The audit then waits
+// for review, or is deleted when no vote was left to reverse.
+func (s *MongoVoteAuditStore) ReverseVotes(ctx context.Context, audit *types.VoteAudit) (*types.VoteAudit, error) {
+	err := s.processPending(ctx, audit, types.AuditPending, func(sessCtx mongo.SessionContext, vote *types.Vote) (bool, error) {
+		undo := *vote
+		undo.Vote = types.VoteNone
+
+		if _, err := s.VoteStore.CastVote(sessCtx, &undo, vote.Vote); err != nil {
+			if errors.Is(err, ErrVoteChanged) || errors.Is(err, ErrNotFound) {
+				return false, nil
+			}
+			return false, err
+		}
+
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.coll.DeleteOne(ctx, bson.M{"_id": audit.ID, "status": types.AuditPending, "pending": bson.M{"$size": 0}, "votes": bson.M{"$size": 0}}); err != nil {
+		return nil, storeError(err)
+	}
+
+	return s.finishPending(ctx, audit.ID, types.AuditPending, types.AuditReversed)
+}
+
+// RestoreVotes casts each pending vote of an audit being restored again
+// with its reputation, every vote in its own transaction that also takes it
+// off the pending list, so a restore cut short resumes where it stopped.
+// Votes their voter has cast again since are left alone.
+func (s *MongoVoteAuditStore) RestoreVotes(ctx context.Context, audit *types.VoteAudit) (*types.VoteAudit, error) {
+	err := s.processPending(ctx, audit, types.AuditRestoring, func(sessCtx mongo.SessionContext, vote *types.Vote) (bool, error) {
+		current, err := s.VoteStore.GetVote(sessCtx, vote.UserID, vote.TargetID)
+		if err != nil {
+			return false, err
+		}
+		if current != types.VoteNone {
+			return true, nil
+		}
+
+		restored := *vote
+		restored.Reviewed = true
+
+		if _, err := s.VoteStore.CastVote(sessCtx, &restored, types.VoteNone); err != nil {
+			return false, err
+		}
+
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.finishPending(ctx, audit.ID, types.AuditRestoring, types.AuditRestored)
+}
+
+// processPending runs fn on every pending vote of the audit, each in a
+// transaction that takes the vote off the pending list, and off the audit
+// too when fn reports it was not kept. The audit must still be in status,
+// otherwise the transaction fails with ErrAuditReviewed.
+func (s *MongoVoteAuditStore) processPending(ctx context.Context, audit *types.VoteAudit, status string, fn func(mongo.SessionContext, *types.Vote) (bool, error)) error {
+	pending := make(map[primitive.ObjectID]bool, len(audit.Pending))
+	for _, id := range audit.Pending {
+		pending[id] = true
+	}
+
+	for _, vote := range audit.Votes {
+		if !pending[vote.ID] {
+			continue
+		}
+
+		_, err := withTransaction(ctx, s.client, func(sessCtx mongo.SessionContext) (interface{}, error) {
+			kept, err := fn(sessCtx, vote)
+			if err != nil {
+				return nil, err
+			}
+
+			update := bson.M{"$pull": bson.M{"pending": vote.ID}}
+			if !kept {
+				update["$pull"] = bson.M{"pending": vote.ID, "votes": bson.M{"_id": vote.ID}}
+			}
+
+			res, err := s.coll.UpdateOne(sessCtx, bson.M{"_id": audit.ID, "status": status, "pending": vote.ID}, update)
+			if err != nil {
+				return nil, storeError(err)
+			}
+			if res.MatchedCount == 0 {
+				return nil, ErrAuditReviewed
+			}
+
+			return nil, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// finishPending moves an audit with no pending votes left from one status
+// to the next and returns it. An audit that is gone was deleted for having
+// no votes and comes back as nil.
+func (s *MongoVoteAuditStore) finishPending(ctx context.Context, id primitive.ObjectID, from, to string) (*types.VoteAudit, error) {
+	filter := bson.M{"_id": id, "status": from, "pending": bson.M{"$size": 0}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var audit types.VoteAudit
+	err := s.coll.FindOneAndUpdate(ctx, filter, bson.M{"$set": bson.M{"status": to}}, opts).Decode(&audit)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		err = s.coll.FindOne(ctx, bson.M{"_id": id}).Decode(&audit)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+	}
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	return &audit, nil
+}
@@ -0,0 +1,540 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/fullstack/dev-overflow/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const VOTECOLL = "votes"
+
+// ErrVoteChanged is returned when the caller's vote changed between reading
+// it and voting. Retrying reads the new vote.
+var ErrVoteChanged = newStoreError(ErrConflict, "vote changed concurrently")
+
+type VoteStore interface {
+	EnsureIndexes(context.Context) error
+	GetVote(context.Context, primitive.ObjectID, primitive.ObjectID) (types.VoteState, error)
+	GetVotes(context.Context, primitive.ObjectID, []primitive.ObjectID) (map[primitive.ObjectID]types.VoteState, error)
+	CastVote(context.Context, *types.Vote, types.VoteState) (*types.VoteResult, error)
+	DeleteVotesByUserID(context.Context, primitive.ObjectID) error
+	GetVotingPairs(context.Context, time.Time, int, ...types.VoteState) ([]*types.VotePair, error)
+	GetPairVotes(context.Context, primitive.ObjectID, primitive.ObjectID, time.Time, ...types.VoteState) ([]*types.Vote, error)
+	MigrateVotes(context.Context) (int64, error)
+	RecomputeCounters(context.Context) error
+	DropMembershipArrays(context.Context) error
+}
+
+type MongoVoteStore struct {
+	client *mongo.Client
+	database *mongo.Database
+	coll *mongo.Collection
+	ReputationStore
+}
+
+func NewMongoVoteStore(client *mongo.Client, reputationStore ReputationStore) *MongoVoteStore {
+	var mongoenvdbname = os.Getenv("MONGO_DB_NAME")
+	return &MongoVoteStore{
+		client: client,
+		database: client.Database(mongoenvdbname),
+		coll: client.Database(mongoenvdbname).Collection(VOTECOLL),
+		ReputationStore: reputationStore,
+	}
+}
+
+// EnsureIndexes creates the unique (user, target) index that keeps a single
+// vote per user and post, the indexes the delete cascades use and the one
+// the fraud detection scans by.
+func (s *MongoVoteStore) EnsureIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "userID", Value: 1}, {Key: "targetID", Value: 1}},
+			Options: options.Index().SetName("vote_user_target").SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "targetID", Value: 1}},
+			Options: options.Index().SetName("vote_target"),
+		},
+		{
+			Keys: bson.D{{Key: "questionID", Value: 1}},
+			Options: options.Index().SetName("vote_question"),
+		},
+		{
+			Keys: bson.D{{Key: "updatedAt", Value: 1}},
+			Options: options.Index().SetName("vote_updated"),
+		},
+	}
+
+	if _, err := s.coll.Indexes().CreateMany(ctx, indexes); err != nil {
+		return storeError(err)
+	}
+
+	return nil
+}
+
+// GetVote returns userID's vote on targetID, VoteNone when there is none.
+func (s *MongoVoteStore) GetVote(ctx context.Context, userID, targetID primitive.ObjectID) (types.VoteState, error) {
+	var vote types.Vote
+	err := s.coll.FindOne(ctx, bson.M{"userID": userID, "targetID": targetID}).Decode(&vote)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return types.VoteNone, nil
+	}
+	if err != nil {
+		return types.VoteNone, storeError(err)
+	}
+
+	return vote.Vote, nil
+}
+
+// GetVotes returns userID's votes on any of targetIDs in one query. Targets
+// the user did not vote on are missing from the map.
+func (s *MongoVoteStore) GetVotes(ctx context.Context, userID primitive.ObjectID, targetIDs []primitive.ObjectID) (map[primitive.ObjectID]types.VoteState, error) {
+	votes := map[primitive.ObjectID]types.VoteState{}
+	if len(targetIDs) == 0 {
+		return votes, nil
+	}
+
+	filter := bson.M{"userID": userID, "targetID": bson.M{"$in": targetIDs}}
+	cursor, err := s.coll.Find(ctx, filter, options.Find().SetProjection(bson.M{"targetID": 1, "vote": 1}))
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	var found []types.Vote
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, storeError(err)
+	}
+
+	for _, vote := range found {
+		votes[vote.TargetID] = vote.Vote
+	}
+
+	return votes, nil
+}
+
+// CastVote moves the user's vote on the target from prev to vote.Vote,
+// applies the difference to the post counters and moves the ledger with it
+// in one transaction. The vote
+// document only changes while it is still prev, and the unique index stops
+// two first votes, so of two racing requests only one applies and the other
+// fails with ErrVoteChanged. Inside another transaction it joins that one.
+func (s *MongoVoteStore) CastVote(ctx context.Context, vote *types.Vote, prev types.VoteState) (*types.VoteResult, error) {
+	result, err := withTransaction(ctx, s.client, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		if err := s.moveVote(sessCtx, vote, prev); err != nil {
+			return nil, err
+		}
+		result, err := s.countVote(sessCtx, vote, prev)
+		if err != nil {
+			return nil, err
+		}
+		return result, s.ReputationStore.RecordVote(sessCtx, vote.Post(), vote.UserID, prev, vote.Vote)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*types.VoteResult), nil
+}
+
+// DeleteVotesByUserID clears every vote of a user being deleted the way they
+// would, so the scores of the posts and the reputation the votes earned move
+// back, each vote in its own transaction. Votes on posts that are gone are
+// just removed.
+func (s *MongoVoteStore) DeleteVotesByUserID(ctx context.Context, userID primitive.ObjectID) error {
+	var votes []*types.Vote
+
+	cursor, err := s.coll.Find(ctx, bson.M{"userID": userID})
+	if err != nil {
+		return storeError(err)
+	}
+
+	if err := cursor.All(ctx, &votes); err != nil {
+		return storeError(err)
+	}
+
+	for _, vote := range votes {
+		undo := *vote
+		undo.Vote = types.VoteNone
+
+		_, err := s.CastVote(ctx, &undo, vote.Vote)
+		switch {
+		case errors.Is(err, ErrVoteChanged):
+			// Changed or cleared since it was read, the next run picks it up.
+		case errors.Is(err, ErrNotFound):
+			if _, err := s.coll.DeleteOne(ctx, bson.M{"_id": vote.ID}); err != nil {
+				return storeError(err)
+			}
+		case err != nil:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *MongoVoteStore) moveVote(ctx context.Context, vote *types.Vote, prev types.VoteState) error {
+	var (
+		now = time.Now().UTC()
+		filter = bson.M{"userID": vote.UserID, "targetID": vote.TargetID, "vote": prev}
+		matched int64
+		err error
+	)
+
+	switch {
+	case prev == vote.Vote && prev == types.VoteNone:
+		var n int64
+		if n, err = s.coll.CountDocuments(ctx, bson.M{"userID": vote.UserID, "targetID": vote.TargetID}); err == nil {
+			matched = 1 - n
+		}
+	case prev == vote.Vote:
+		matched, err = s.coll.CountDocuments(ctx, filter)
+	case prev == types.VoteNone:
+		vote.CreatedAt, vote.UpdatedAt = now, now
+		res, insertErr := s.coll.InsertOne(ctx, vote)
+		if mongo.IsDuplicateKeyError(insertErr) {
+			return ErrVoteChanged
+		}
+		if insertErr == nil {
+			vote.ID = res.InsertedID.(primitive.ObjectID)
+			matched = 1
+		}
+		err = insertErr
+	case vote.Vote == types.VoteNone:
+		var res *mongo.DeleteResult
+		if res, err = s.coll.DeleteOne(ctx, filter); err == nil {
+			matched = res.DeletedCount
+		}
+	default:
+		var res *mongo.UpdateResult
+		update := bson.M{"$set": bson.M{"vote": vote.Vote, "updatedAt": now}}
+		if res, err = s.coll.UpdateOne(ctx, filter, update); err == nil {
+			matched = res.MatchedCount
+		}
+	}
+
+	if err != nil {
+		return storeError(err)
+	}
+
+	if matched == 0 {
+		return ErrVoteChanged
+	}
+
+	return nil
+}
+
+// countVote applies the vote change to the target's score and vote counts
+// and returns them.
+func (s *MongoVoteStore) countVote(ctx context.Context, vote *types.Vote, prev types.VoteState) (*types.VoteResult, error) {
+	coll := s.database.Collection(QUESTIONCOLL)
+	if vote.TargetType == types.VoteOnAnswer {
+		coll = s.database.Collection(ANSWERCOLL)
+	}
+
+	inc := bson.M{}
+	if delta := int(vote.Vote - prev); delta != 0 {
+		inc["score"] = delta
+	}
+	for state, field := range map[types.VoteState]string{types.VoteUp: "upvoteCount", types.VoteDown: "downvoteCount"} {
+		switch state {
+		case prev:
+			inc[field] = -1
+		case vote.Vote:
+			inc[field] = 1
+		}
+	}
+
+	var (
+		counts struct {
+			Score int `bson:"score"`
+			UpvoteCount int `bson:"upvoteCount"`
+			DownvoteCount int `bson:"downvoteCount"`
+		}
+		projection = bson.M{"score": 1, "upvoteCount": 1, "downvoteCount": 1}
+		filter = bson.M{"_id": vote.TargetID}
+		err error
+	)
+
+	if prev == vote.Vote {
+		err = coll.FindOne(ctx, filter, options.FindOne().SetProjection(projection)).Decode(&counts)
+	} else {
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After).SetProjection(projection)
+		err = coll.FindOneAndUpdate(ctx, filter, bson.M{"$inc": inc}, opts).Decode(&counts)
+	}
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	return &types.VoteResult{
+		PostID: vote.TargetID,
+		Vote: vote.Vote,
+		Score: counts.Score,
+		Upvotes: counts.UpvoteCount,
+		Downvotes: counts.DownvoteCount,
+	}, nil
+}
+
+// suspectVotes matches the votes cast since the given time that a moderator
+// has not cleared, limited to states when any are given.
+func suspectVotes(since time.Time, states []types.VoteState) bson.M {
+	filter := bson.M{
+		"updatedAt": bson.M{"$gte": since},
+		"reviewed": bson.M{"$ne": true},
+	}
+	if len(states) > 0 {
+		filter["vote"] = bson.M{"$in": states}
+	}
+	return filter
+}
+
+// GetVotingPairs counts the votes each user cast on each other user's posts
+// since the given time and returns the pairs with at least minVotes.
+func (s *MongoVoteStore) GetVotingPairs(ctx context.Context, since time.Time, minVotes int, states ...types.VoteState) ([]*types.VotePair, error) {
+	pipeline := []bson.M{
+		{"$match": suspectVotes(since, states)},
+		{"$group": bson.M{
+			"_id": bson.M{"voterID": "$userID", "ownerID": "$ownerID"},
+			"count": bson.M{"$sum": 1},
+		}},
+		{"$match": bson.M{"count": bson.M{"$gte": minVotes}}},
+		{"$project": bson.M{"_id": 0, "voterID": "$_id.voterID", "ownerID": "$_id.ownerID", "count": 1}},
+		{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "voterID", Value: 1}, {Key: "ownerID", Value: 1}}},
+	}
+
+	cursor, err := s.coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	pairs := []*types.VotePair{}
+	if err := cursor.All(ctx, &pairs); err != nil {
+		return nil, storeError(err)
+	}
+
+	return pairs, nil
+}
+
+// GetPairVotes returns the votes voterID cast on ownerID's posts since the
+// given time, oldest first.
+func (s *MongoVoteStore) GetPairVotes(ctx context.Context, voterID, ownerID primitive.ObjectID, since time.Time, states ...types.VoteState) ([]*types.Vote, error) {
+	filter := suspectVotes(since, states)
+	filter["userID"] = voterID
+	filter["ownerID"] = ownerID
+
+	cursor, err := s.coll.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "updatedAt", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	votes := []*types.Vote{}
+	if err := cursor.All(ctx, &votes); err != nil {
+		return nil, storeError(err)
+	}
+
+	return votes, nil
+}
+
+// MigrateVotes copies the upvotes and downvotes arrays once embedded in
+// questions and answers into the votes collection and drops the arrays. It
+// is idempotent, votes already migrated are kept. The original vote time is
+// unknown, so migrated votes carry the post's creation time.
+func (s *MongoVoteStore) MigrateVotes(ctx context.Context) (int64, error) {
+	if err := s.EnsureIndexes(ctx); err != nil {
+		return 0, err
+	}
+
+	before, err := s.coll.EstimatedDocumentCount(ctx)
+	if err != nil {
+		return 0, storeError(err)
+	}
+
+	targets := []struct {
+		coll string
+		targetType types.VoteTarget
+		questionID string
+	}{
+		{QUESTIONCOLL, types.VoteOnQuestion, "$_id"},
+		{ANSWERCOLL, types.VoteOnAnswer, "$questionID"},
+	}
+
+	hasArrays := bson.M{"$or": []bson.M{
+		{"upvotes": bson.M{"$type": "array"}},
+		{"downvotes": bson.M{"$type": "array"}},
+	}}
+
+	for _, target := range targets {
+		votesOf := func(field string, vote types.VoteState) bson.M {
+			return bson.M{"$map": bson.M{
+				"input": bson.M{"$ifNull": []any{"$" + field, []any{}}},
+				"as": "userID",
+				"in": bson.M{"userID": "$$userID", "vote": vote},
+			}}
+		}
+
+		pipeline := []bson.M{
+			{"$match": hasArrays},
+			{"$project": bson.M{
+				"_id": 0,
+				"targetID": "$_id",
+				"ownerID": "$userID",
+				"questionID": target.questionID,
+				"createdAt": "$createdAt",
+				"votes": bson.M{"$concatArrays": []any{votesOf("upvotes", types.VoteUp), votesOf("downvotes", types.VoteDown)}},
+			}},
+			{"$unwind": "$votes"},
+			{"$project": bson.M{
+				"userID": "$votes.userID",
+				"targetID": 1,
+				"targetType": bson.M{"$literal": target.targetType},
+				"questionID": 1,
+				"ownerID": 1,
+				"vote": "$votes.vote",
+				"createdAt": 1,
+				"updatedAt": "$createdAt",
+			}},
+			{"$merge": bson.M{
+				"into": VOTECOLL,
+				"on": []string{"userID", "targetID"},
+				"whenMatched": "keepExisting",
+				"whenNotMatched": "insert",
+			}},
+		}
+
+		cursor, err := s.database.Collection(target.coll).Aggregate(ctx, pipeline)
+		if err != nil {
+			return 0, storeError(err)
+		}
+		cursor.Close(ctx)
+
+		if _, err := s.database.Collection(target.coll).UpdateMany(ctx, hasArrays, bson.M{"$unset": bson.M{"upvotes": "", "downvotes": ""}}); err != nil {
+			return 0, storeError(err)
+		}
+	}
+
+	after, err := s.coll.EstimatedDocumentCount(ctx)
+	if err != nil {
+		return 0, storeError(err)
+	}
+
+	return after - before, nil
+}
+
+// RecomputeCounters rebuilds every maintained counter from its source of
+// truth: post scores from the votes collection, answer and question counts
+// from the answers and questions that reference their owner, follower
+// counts from the tag followers and comment upvote counts from the comment
+// upvoters. Writes racing with it can be lost, run it
+// with the API stopped.
+func (s *MongoVoteStore) RecomputeCounters(ctx context.Context) error {
+	for coll, targetType := range map[string]types.VoteTarget{QUESTIONCOLL: types.VoteOnQuestion, ANSWERCOLL: types.VoteOnAnswer} {
+		reset := bson.M{"$set": bson.M{"score": 0, "upvoteCount": 0, "downvoteCount": 0}}
+		if _, err := s.database.Collection(coll).UpdateMany(ctx, bson.M{}, reset); err != nil {
+			return storeError(err)
+		}
+
+		countOf := func(vote types.VoteState) bson.M {
+			return bson.M{"$sum": bson.M{"$cond": []any{bson.M{"$eq": []any{"$vote", vote}}, 1, 0}}}
+		}
+
+		pipeline := []bson.M{
+			{"$match": bson.M{"targetType": targetType}},
+			{"$group": bson.M{
+				"_id": "$targetID",
+				"score": bson.M{"$sum": "$vote"},
+				"upvoteCount": countOf(types.VoteUp),
+				"downvoteCount": countOf(types.VoteDown),
+			}},
+			{"$merge": bson.M{
+				"into": coll,
+				"on": "_id",
+				"whenMatched": "merge",
+				"whenNotMatched": "discard",
+			}},
+		}
+
+		cursor, err := s.coll.Aggregate(ctx, pipeline)
+		if err != nil {
+			return storeError(err)
+		}
+		cursor.Close(ctx)
+	}
+
+	reset := map[string]bson.M{
+		QUESTIONCOLL: {"answerCount": 0},
+		USERCOLL: {"questionCount": 0, "answerCount": 0},
+		TAGCOLL: {"questionCount": 0},
+	}
+
+	for coll, set := range reset {
+		if _, err := s.database.Collection(coll).UpdateMany(ctx, bson.M{}, bson.M{"$set": set}); err != nil {
+			return storeError(err)
+		}
+	}
+
+	counts := []struct {
+		from string
+		into string
+		group []bson.M
+	}{
+		{ANSWERCOLL, QUESTIONCOLL, []bson.M{{"$group": bson.M{"_id": "$questionID", "answerCount": bson.M{"$sum": 1}}}}},
+		{ANSWERCOLL, USERCOLL, []bson.M{{"$group": bson.M{"_id": "$userID", "answerCount": bson.M{"$sum": 1}}}}},
+		{QUESTIONCOLL, USERCOLL, []bson.M{{"$group": bson.M{"_id": "$userID", "questionCount": bson.M{"$sum": 1}}}}},
+		{QUESTIONCOLL, TAGCOLL, []bson.M{
+			{"$unwind": "$tags"},
+			{"$group": bson.M{"_id": "$tags", "questionCount": bson.M{"$sum": 1}}},
+		}},
+	}
+
+	for _, count := range counts {
+		pipeline := append(count.group, bson.M{"$merge": bson.M{
+			"into": count.into,
+			"on": "_id",
+			"whenMatched": "merge",
+			"whenNotMatched": "discard",
+		}})
+
+		cursor, err := s.database.Collection(count.from).Aggregate(ctx, pipeline)
+		if err != nil {
+			return storeError(err)
+		}
+		cursor.Close(ctx)
+	}
+
+	followerCount := bson.M{"$size": bson.M{"$setUnion": []any{bson.M{"$ifNull": []any{"$followers", []any{}}}, []any{}}}}
+	if _, err := s.database.Collection(TAGCOLL).UpdateMany(ctx, bson.M{}, []bson.M{{"$set": bson.M{"followerCount": followerCount}}}); err != nil {
+		return storeError(err)
+	}
+
+	upvoteCount := bson.M{"$size": bson.M{"$setUnion": []any{bson.M{"$ifNull": []any{"$upvotes", []any{}}}, []any{}}}}
+	if _, err := s.database.Collection(COMMENTCOLL).UpdateMany(ctx, bson.M{}, []bson.M{{"$set": bson.M{"upvoteCount": upvoteCount}}}); err != nil {
+		return storeError(err)
+	}
+
+	return nil
+}
+
+// DropMembershipArrays removes the answers and questions arrays questions,
+// users and tags used to embed. Membership is read from answers.questionID,
+// questions.userID and questions.tags instead.
+func (s *MongoVoteStore) DropMembershipArrays(ctx context.Context) error {
+	arrays := map[string]bson.M{
+		QUESTIONCOLL: {"answers": ""},
+		USERCOLL: {"questions": "", "answers": ""},
+		TAGCOLL: {"questions": ""},
+	}
+
+	for coll, fields := range arrays {
+		if _, err := s.database.Collection(coll).UpdateMany(ctx, bson.M{}, bson.M{"$unset": fields}); err != nil {
+			return storeError(err)
+		}
+	}
+
+	return nil
+}
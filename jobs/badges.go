@@ -0,0 +1,66 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/fullstack/dev-overflow/db"
+	"github.com/fullstack/dev-overflow/types"
+)
+
+// badgeRunHour is the UTC hour the nightly badge evaluation runs at, after
+// the vote fraud detector so reversed votes no longer count.
+const badgeRunHour = 4
+
+// BadgeAwarder evaluates the criteria of every badge in the catalog and
+// awards the users who newly meet them.
+type BadgeAwarder struct {
+	badgeStore db.BadgeStore
+}
+
+func NewBadgeAwarder(badgeStore db.BadgeStore) *BadgeAwarder {
+	return &BadgeAwarder{
+		badgeStore: badgeStore,
+	}
+}
+
+// Run awards badges every night until ctx is done.
+func (j *BadgeAwarder) Run(ctx context.Context) {
+	for {
+		timer := time.NewTimer(time.Until(nextRun(time.Now().UTC(), badgeRunHour)))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := j.AwardBadges(ctx, time.Now().UTC()); err != nil {
+			log.Println("badge awarder:", err)
+		}
+	}
+}
+
+// AwardBadges evaluates each badge as of now. Awards are idempotent, so
+// users already holding a badge are simply skipped by the store.
+func (j *BadgeAwarder) AwardBadges(ctx context.Context, now time.Time) error {
+	for _, badge := range types.Badges {
+		awards, err := j.badgeStore.FindQualifying(ctx, badge, now)
+		if err != nil {
+			return err
+		}
+
+		awarded, err := j.badgeStore.AwardBadges(ctx, awards)
+		if err != nil {
+			return err
+		}
+
+		if awarded > 0 {
+			log.Printf("badge awarder: awarded %s %d times", badge.ID, awarded)
+		}
+	}
+
+	return nil
+}
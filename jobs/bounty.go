@@ -0,0 +1,73 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/fullstack/dev-overflow/db"
+	"github.com/fullstack/dev-overflow/types"
+)
+
+const bountyCandidateLimit = 20
+
+// BountyExpirer closes bounties whose expiry has passed, awarding them to
+// the top voted answer.
+type BountyExpirer struct {
+	bountyStore db.BountyStore
+	answerStore db.AnswerStore
+	interval time.Duration
+}
+
+func NewBountyExpirer(bountyStore db.BountyStore, answerStore db.AnswerStore, interval time.Duration) *BountyExpirer {
+	return &BountyExpirer{
+		bountyStore: bountyStore,
+		answerStore: answerStore,
+		interval: interval,
+	}
+}
+
+// Run expires bounties every interval until ctx is done.
+func (j *BountyExpirer) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.ExpireBounties(ctx); err != nil {
+			log.Println("bounty expirer:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *BountyExpirer) ExpireBounties(ctx context.Context) error {
+	bounties, err := j.bountyStore.GetExpiredBounties(ctx, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	for _, bounty := range bounties {
+		// The top voted answers come first, the accepted one is pinned on top
+		// of them.
+		answers, err := j.answerStore.GetAnswersOfQuestion(ctx, bounty.QuestionID.Hex(), &db.AnswerQueryParams{
+			Sort: db.AnswerSortVotes,
+			Limit: bountyCandidateLimit,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = j.bountyStore.CloseBounty(ctx, bounty, types.AutoAwardAnswer(bounty, answers.Answers))
+		if err != nil && !errors.Is(err, db.ErrBountyClosed) {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fullstack/dev-overflow/db"
+	"github.com/fullstack/dev-overflow/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeBountyStore only implements what the expiry job needs, the embedded
+// interface panics on anything else, including the sponsor's AwardBounty.
+type fakeBountyStore struct {
+	db.BountyStore
+	bounties map[primitive.ObjectID]*types.Bounty
+}
+
+func (s *fakeBountyStore) GetExpiredBounties(_ context.Context, now time.Time) ([]*types.Bounty, error) {
+	expired := []*types.Bounty{}
+	for _, bounty := range s.bounties {
+		if bounty.Status == types.BountyOpen && !bounty.ExpiresAt.After(now) {
+			expired = append(expired, bounty)
+		}
+	}
+	return expired, nil
+}
+
+// CloseBounty mimics the conditional status flip of the store.
+func (s *fakeBountyStore) CloseBounty(_ context.Context, bounty *types.Bounty, answer *types.Answer) (*types.Bounty, error) {
+	stored, ok := s.bounties[bounty.ID]
+	if !ok || stored.Status != types.BountyOpen {
+		return nil, db.ErrBountyClosed
+	}
+
+	now := time.Now().UTC()
+	stored.Status = types.BountyExpired
+	stored.ClosedAt = &now
+	if answer != nil {
+		stored.Status = types.BountyAwarded
+		stored.AnswerID = &answer.ID
+	}
+	return stored, nil
+}
+
+type fakeAnswerStore struct {
+	db.AnswerStore
+	answers []*types.Answer
+}
+
+func (s *fakeAnswerStore) GetAnswersOfQuestion(_ context.Context, _ string, _ *db.AnswerQueryParams) (*types.AnswerList, error) {
+	return &types.AnswerList{Answers: s.answers}, nil
+}
+
+func TestExpireBounties(t *testing.T) {
+	sponsorID := primitive.NewObjectID()
+	scored := &types.Answer{ID: primitive.NewObjectID(), UserID: primitive.NewObjectID(), Score: 3}
+
+	tests := []struct {
+		name string
+		answers []*types.Answer
+		wantStatus string
+		wantAnswer *primitive.ObjectID
+	}{
+		{name: "scored answer is awarded", answers: []*types.Answer{scored}, wantStatus: types.BountyAwarded, wantAnswer: &scored.ID},
+		{name: "no answers lapse", answers: []*types.Answer{}, wantStatus: types.BountyExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bounty := &types.Bounty{
+				ID: primitive.NewObjectID(),
+				QuestionID: primitive.NewObjectID(),
+				SponsorID: sponsorID,
+				Amount: 50,
+				Status: types.BountyOpen,
+				ExpiresAt: time.Now().UTC().Add(-time.Hour),
+			}
+			bountyStore := &fakeBountyStore{bounties: map[primitive.ObjectID]*types.Bounty{bounty.ID: bounty}}
+			job := NewBountyExpirer(bountyStore, &fakeAnswerStore{answers: tt.answers}, time.Minute)
+
+			if err := job.ExpireBounties(context.Background()); err != nil {
+				t.Fatalf("ExpireBounties() error = %v", err)
+			}
+
+			if bounty.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", bounty.Status, tt.wantStatus)
+			}
+			if (bounty.AnswerID == nil) != (tt.wantAnswer == nil) ||
+				(tt.wantAnswer != nil && *bounty.AnswerID != *tt.wantAnswer) {
+				t.Errorf("answerID = %v, want %v", bounty.AnswerID, tt.wantAnswer)
+			}
+			if bounty.ClosedAt == nil {
+				t.Error("closedAt was not set")
+			}
+		})
+	}
+}
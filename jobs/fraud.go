@@ -0,0 +1,184 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/fullstack/dev-overflow/db"
+	"github.com/fullstack/dev-overflow/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// fraudRunHour is the UTC hour the nightly analysis runs at.
+	fraudRunHour = 3
+	// maxResumedAudits caps the unfinished audits one run picks up, the
+	// rest wait for the next run.
+	maxResumedAudits = 100
+)
+
+// VoteFraudDetector looks for serial voting and voting rings in the votes
+// cast recently, reverses the offending votes with their reputation and
+// records an audit for moderators to review.
+type VoteFraudDetector struct {
+	voteStore db.VoteStore
+	auditStore db.VoteAuditStore
+	rules types.FraudRules
+}
+
+func NewVoteFraudDetector(voteStore db.VoteStore, auditStore db.VoteAuditStore, rules types.FraudRules) *VoteFraudDetector {
+	return &VoteFraudDetector{
+		voteStore: voteStore,
+		auditStore: auditStore,
+		rules: rules,
+	}
+}
+
+// Run analyses the votes every night until ctx is done.
+func (j *VoteFraudDetector) Run(ctx context.Context) {
+	for {
+		timer := time.NewTimer(time.Until(nextRun(time.Now().UTC(), fraudRunHour)))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := j.DetectFraud(ctx, time.Now().UTC()); err != nil {
+			log.Println("vote fraud detector:", err)
+		}
+	}
+}
+
+// nextRun returns the next time it is hour o'clock after now.
+func nextRun(now time.Time, hour int) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, time.UTC)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// DetectFraud finishes the audits left unfinished, then runs both analyses
+// as of now. Serial voting is looked for in the last day, plus one window so
+// bursts spanning two runs are caught.
+func (j *VoteFraudDetector) DetectFraud(ctx context.Context, now time.Time) error {
+	if err := j.resumeAudits(ctx); err != nil {
+		return err
+	}
+
+	if err := j.detectSerialVoting(ctx, now.Add(-24*time.Hour-j.rules.SerialWindow)); err != nil {
+		return err
+	}
+
+	return j.detectVotingRings(ctx, now.Add(-j.rules.RingLookback))
+}
+
+func (j *VoteFraudDetector) detectSerialVoting(ctx context.Context, since time.Time) error {
+	pairs, err := j.voteStore.GetVotingPairs(ctx, since, j.rules.SerialMinVotes)
+	if err != nil {
+		return err
+	}
+
+	for _, pair := range pairs {
+		votes, err := j.voteStore.GetPairVotes(ctx, pair.VoterID, pair.OwnerID, since)
+		if err != nil {
+			return err
+		}
+
+		serial := types.SerialVotes(votes, j.rules.SerialWindow, j.rules.SerialMinVotes)
+		if len(serial) == 0 {
+			continue
+		}
+
+		voters := []primitive.ObjectID{pair.VoterID}
+		owners := []primitive.ObjectID{pair.OwnerID}
+		if err := j.reverse(ctx, types.FraudSerialVoting, voters, owners, serial); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// detectVotingRings reverses the upvotes exchanged inside each ring.
+func (j *VoteFraudDetector) detectVotingRings(ctx context.Context, since time.Time) error {
+	pairs, err := j.voteStore.GetVotingPairs(ctx, since, j.rules.RingMinVotes, types.VoteUp)
+	if err != nil {
+		return err
+	}
+
+	for _, ring := range types.VotingRings(pairs, j.rules.RingMaxSize) {
+		votes := []*types.Vote{}
+		for _, voterID := range ring {
+			for _, ownerID := range ring {
+				if voterID == ownerID {
+					continue
+				}
+
+				upvotes, err := j.voteStore.GetPairVotes(ctx, voterID, ownerID, since, types.VoteUp)
+				if err != nil {
+					return err
+				}
+				votes = append(votes, upvotes...)
+			}
+		}
+
+		if err := j.reverse(ctx, types.FraudVotingRing, ring, ring, votes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reverse audits the votes, still pending, and then reverses them with
+// their reputation. Votes changed or removed since they were read are left
+// out of the audit.
+func (j *VoteFraudDetector) reverse(ctx context.Context, pattern types.FraudPattern, voters, owners []primitive.ObjectID, votes []*types.Vote) error {
+	if len(votes) == 0 {
+		return nil
+	}
+
+	audit, err := j.auditStore.CreateAudit(ctx, &types.VoteAudit{
+		Pattern: pattern,
+		VoterIDs: voters,
+		OwnerIDs: owners,
+		Votes: votes,
+		DetectedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = j.auditStore.ReverseVotes(ctx, audit)
+	return err
+}
+
+// resumeAudits finishes the audits a previous run or a moderator's restore
+// left with pending votes.
+func (j *VoteFraudDetector) resumeAudits(ctx context.Context) error {
+	for _, status := range []string{types.AuditPending, types.AuditRestoring} {
+		audits, err := j.auditStore.GetAudits(ctx, &db.VoteAuditQueryParams{Status: status, Limit: maxResumedAudits})
+		if err != nil {
+			return err
+		}
+
+		for _, audit := range audits.Audits {
+			if status == types.AuditPending {
+				_, err = j.auditStore.ReverseVotes(ctx, audit)
+			} else {
+				_, err = j.auditStore.RestoreVotes(ctx, audit)
+			}
+			if err != nil && !errors.Is(err, db.ErrAuditReviewed) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -4,11 +4,17 @@ import (
 	"context"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
+	"github.com/clerkinc/clerk-sdk-go/clerk"
 	"github.com/fullstack/dev-overflow/api"
 	"github.com/fullstack/dev-overflow/db"
+	"github.com/fullstack/dev-overflow/jobs"
+	"github.com/fullstack/dev-overflow/types"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
 	"github.com/joho/godotenv"
 	"github.com/sashabaranov/go-openai"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -24,18 +30,41 @@ var (
 func main() {
 	mongoEndpoint := os.Getenv("MONGO_DB_URL")
 	openAIAPIKey := os.Getenv("OPENAI_API_KEY")
+	clerkSecretKey := os.Getenv("CLERK_SECRET_KEY")
+	clerkWebhookSecret := os.Getenv("CLERK_WEBHOOK_SECRET")
+	closeVoteThreshold := types.DefaultCloseVoteThreshold
+	if n, err := strconv.Atoi(os.Getenv("CLOSE_VOTE_THRESHOLD")); err == nil && n > 0 {
+		closeVoteThreshold = n
+	}
 	client, err := mongo.Connect(context.TODO(), options.Client().ApplyURI(mongoEndpoint))
 	if err != nil {
 		log.Fatal(err)
 	} 
 	openAIClient := openai.NewClient(openAIAPIKey)
+	clerkClient, err := clerk.NewClient(clerkSecretKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+	svixVerifier, err := api.NewSvixVerifier(clerkWebhookSecret)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	var (
 		userStore = db.NewMongoUserStore(client)
 		tagStore = db.NewMongoTagStore(client)
-		answerStore = db.NewMongoAnswerStore(client, userStore)
-		questionStore = db.NewMongoQuestionStore(client, tagStore, userStore)
+		reputationStore = db.NewMongoReputationStore(client)
+		answerStore = db.NewMongoAnswerStore(client, userStore, reputationStore)
+		questionStore = db.NewMongoQuestionStore(client, tagStore, userStore, answerStore, reputationStore)
 		interactionStore = db.NewMongoInteractionStore(client, userStore, questionStore)
+		revisionStore = db.NewMongoRevisionStore(client)
+		searchStore = db.NewMongoSearchStore(client, userStore, tagStore)
+		bountyStore = db.NewMongoBountyStore(client, reputationStore)
+		commentStore = db.NewMongoCommentStore(client)
+		voteStore = db.NewMongoVoteStore(client, reputationStore)
+		voteAuditStore = db.NewMongoVoteAuditStore(client, voteStore)
+		quotaStore = db.NewMongoQuotaStore(client)
+		badgeStore = db.NewMongoBadgeStore(client)
 
 		store = &db.Store{
 			Question: questionStore,
@@ -43,34 +72,113 @@ func main() {
 			Tag: tagStore,
 			Answer: answerStore,
 			Interaction: interactionStore,
+			Revision: revisionStore,
+			Search: searchStore,
+			Reputation: reputationStore,
+			Bounty: bountyStore,
+			Comment: commentStore,
+			Vote: voteStore,
+			VoteAudit: voteAuditStore,
+			Quota: quotaStore,
+			Badge: badgeStore,
 		}
 
 		openAIHandler = api.NewOpenAIHandler(openAIClient)
-		questionHandler = api.NewQuestionHandler(store.Question, store.User, store.Tag, store.Answer)
-		userHandler = api.NewUserHandler(store.User, store.Tag, store.Question)
+		questionHandler = api.NewQuestionHandler(store.Question, store.User, store.Tag, store.Answer, store.Revision, store.Reputation, store.Search, store.Comment, store.Vote, store.Quota)
+		userHandler = api.NewUserHandler(store.User, store.Tag, store.Question, store.Answer, store.Vote)
 		tagHandler = api.NewTagHandler(store.Tag, store.User)
-		answerHandler = api.NewAnswerHandler(store.Answer, store.Question, store.User)
-		interactionHandler = api.NewInteractionHandler(store.Interaction)
+		answerHandler = api.NewAnswerHandler(store.Answer, store.Question, store.User, store.Interaction, store.Reputation, store.Comment, store.Revision, store.Vote, store.Quota)
+		interactionHandler = api.NewInteractionHandler(store.Interaction, store.User)
+		webhookHandler = api.NewWebhookHandler(svixVerifier, store.User, store.Tag, store.Question, store.Answer, store.Vote)
+		searchHandler = api.NewSearchHandler(store.Search)
+		reputationHandler = api.NewReputationHandler(store.Reputation, store.User)
+		closeHandler = api.NewCloseHandler(store.Question, closeVoteThreshold)
+		commentHandler = api.NewCommentHandler(store.Comment, store.Question, store.Answer, store.Interaction, store.Quota)
+		bountyHandler = api.NewBountyHandler(store.Bounty, store.Question, store.Answer)
+		adminHandler = api.NewAdminHandler(store.User, store.Tag)
+		voteAuditHandler = api.NewVoteAuditHandler(store.VoteAudit)
+		privilegeHandler = api.NewPrivilegeHandler(store.Quota)
+		badgeHandler = api.NewBadgeHandler(store.Badge, store.User)
 		app = fiber.New(config)
 		auth = app.Group("/api")
 		apiv1 = app.Group("/api/v1")
 	)
 
-	app.Use(cors.New())
+	if err := store.User.EnsureIndexes(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := store.Tag.EnsureIndexes(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := store.Question.EnsureIndexes(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := store.Answer.EnsureIndexes(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := store.Revision.EnsureIndexes(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := store.Reputation.EnsureIndexes(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := store.Search.EnsureIndexes(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := store.Vote.EnsureIndexes(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := store.Comment.EnsureIndexes(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := store.Quota.EnsureIndexes(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := store.Badge.EnsureIndexes(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+
+	go jobs.NewBountyExpirer(store.Bounty, store.Answer, time.Minute).Run(context.Background())
+	go jobs.NewVoteFraudDetector(store.Vote, store.VoteAudit, types.DefaultFraudRules).Run(context.Background())
+	go jobs.NewBadgeAwarder(store.Badge).Run(context.Background())
+
+	app.Use(requestid.New())
+	app.Use(cors.New(cors.Config{ExposeHeaders: fiber.HeaderXRequestID}))
+	apiv1.Use(api.JWTAuthentication(clerkClient, store.User))
+	apiv1.Use(api.TrackVisits(store.Interaction))
+	admin := apiv1.Group("/admin", api.RequirePermission(types.PermAccessAdmin))
+
 	// Question Handler
 	apiv1.Get("/question/:id", questionHandler.HandleGetQuestionByID)
 	apiv1.Get("/question", questionHandler.HandleGetQuestions)
 	apiv1.Get("/question/user/:id", questionHandler.HandleGetQuestionsByUserID)
+	apiv1.Post("/question/similar", questionHandler.HandleGetSimilarQuestions)
 	apiv1.Post("/ask-question", questionHandler.HandleAskQuestion)
+	apiv1.Get("/question/:id/revisions", questionHandler.HandleGetQuestionRevisions)
+	apiv1.Get("/question/:id/revisions/diff", questionHandler.HandleGetQuestionRevisionDiff)
 	apiv1.Post("/question/:id/vote", questionHandler.HandleQuestionVote)
+	apiv1.Put("/question/:id", questionHandler.HandleEditQuestion)
+	apiv1.Post("/question/:id/close", closeHandler.HandleCloseQuestion)
+	apiv1.Post("/question/:id/reopen", closeHandler.HandleReopenQuestion)
 	apiv1.Delete("/question/:_id", questionHandler.HandleDeleteQuestionByID)
 	
 	// User Handler
 	app.Get("/", userHandler.HandleSayHello)
+	apiv1.Get("/user/me", userHandler.HandleGetMe)
+	apiv1.Get("/user/me/privileges", privilegeHandler.HandleGetPrivileges)
 	apiv1.Get("/user/:clerkID", userHandler.HandleGetUserByID)
 	apiv1.Get("/user", userHandler.HandleGetUsers)
 	apiv1.Get("/user/:clerkID/saved-questions", questionHandler.HandleGetSavedQuestions)
-	auth.Post("/sign-up", userHandler.HandleCreateUser)
 	apiv1.Post("/user/save-question", userHandler.HandleSaveQuestion)
 	apiv1.Put("/user/:clerkID", userHandler.HandleUpdateUser)
 	apiv1.Delete("/user/:clerkID", userHandler.HandleDeleteUser)
@@ -88,11 +196,58 @@ func main() {
 	apiv1.Get("/question/:id/answers", answerHandler.HandleGetAnswersOfQuestion)
 	apiv1.Get("/answer/user/:id", answerHandler.HandleGetAnswersByUserID)
 	apiv1.Post("/answer/:id/vote", answerHandler.HandleAnswerVote)
+	apiv1.Post("/answer/:id/accept", answerHandler.HandleAcceptAnswer)
+	apiv1.Delete("/answer/:id/accept", answerHandler.HandleUnacceptAnswer)
 	apiv1.Post("/answer-question", answerHandler.HandleCreateAnswer)
+	apiv1.Get("/answer/:id/revisions", answerHandler.HandleGetAnswerRevisions)
+	apiv1.Get("/answer/:id/revisions/diff", answerHandler.HandleGetAnswerRevisionDiff)
+	apiv1.Post("/answer/:id/rollback", answerHandler.HandleRollbackAnswer)
+	apiv1.Put("/answer/:id", answerHandler.HandleEditAnswer)
+	apiv1.Delete("/answer/:id", answerHandler.HandleDeleteAnswer)
 
 	// Interaction Handler
+	apiv1.Get("/user/:clerkID/activity", interactionHandler.HandleGetUserActivity)
 	apiv1.Post("/question/view", interactionHandler.HandleCreateViewInteraction)
 
+	// Reputation Handler
+	apiv1.Get("/user/:clerkID/reputation", reputationHandler.HandleGetReputationHistory)
+
+	// Comment Handler
+	apiv1.Get("/question/:id/comments", commentHandler.HandleGetQuestionComments)
+	apiv1.Get("/answer/:id/comments", commentHandler.HandleGetAnswerComments)
+	apiv1.Post("/question/:id/comments", commentHandler.HandleCreateQuestionComment)
+	apiv1.Post("/answer/:id/comments", commentHandler.HandleCreateAnswerComment)
+	apiv1.Put("/comment/:id", commentHandler.HandleEditComment)
+	apiv1.Delete("/comment/:id", commentHandler.HandleDeleteComment)
+	apiv1.Post("/comment/:id/upvote", commentHandler.HandleUpvoteComment)
+	apiv1.Delete("/comment/:id/upvote", commentHandler.HandleRemoveCommentUpvote)
+
+	// Bounty Handler
+	apiv1.Post("/question/:id/bounty", bountyHandler.HandleCreateBounty)
+	apiv1.Post("/question/:id/bounty/award", bountyHandler.HandleAwardBounty)
+
+	// Badge Handler
+	apiv1.Get("/badges", badgeHandler.HandleGetBadges)
+	apiv1.Get("/badges/recent", badgeHandler.HandleGetRecentAwards)
+	apiv1.Get("/user/:clerkID/badges", badgeHandler.HandleGetUserBadges)
+
+	// Search Handler
+	apiv1.Get("/search", searchHandler.HandleSearch)
+
+	// Admin Handler
+	admin.Get("/user/:clerkID", api.RequirePermission(types.PermViewPrivateFields), adminHandler.HandleGetUser)
+	admin.Put("/user/:clerkID", api.RequirePermission(types.PermManageRoles), adminHandler.HandleUpdateUser)
+	admin.Put("/user/:clerkID/role", api.RequirePermission(types.PermManageRoles), adminHandler.HandleUpdateUserRole)
+	admin.Put("/tag/:_id", api.RequirePermission(types.PermEditTags), adminHandler.HandleEditTag)
+	admin.Delete("/question/:_id", api.RequirePermission(types.PermModeratePosts), questionHandler.HandleDeleteQuestionByID)
+	admin.Delete("/answer/:id", api.RequirePermission(types.PermModeratePosts), answerHandler.HandleDeleteAnswer)
+	admin.Get("/vote-audits", api.RequirePermission(types.PermModeratePosts), voteAuditHandler.HandleGetVoteAudits)
+	admin.Get("/vote-audits/:id", api.RequirePermission(types.PermModeratePosts), voteAuditHandler.HandleGetVoteAudit)
+	admin.Post("/vote-audits/:id/review", api.RequirePermission(types.PermModeratePosts), voteAuditHandler.HandleReviewVoteAudit)
+
+	// Webhook Handler
+	auth.Post("/webhooks/clerk", webhookHandler.HandleClerkWebhook)
+
 	// OpenAI Handler
 	apiv1.Post("/chat-gpt", openAIHandler.HandleChatGPT)
 
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/fullstack/dev-overflow/db"
+	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Recomputes every user's reputation from the reputation_events ledger.
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Fatal(err)
+	}
+
+	mongoClient, err := mongo.Connect(context.TODO(), options.Client().ApplyURI(os.Getenv("MONGO_DB_URL")))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	reputationStore := db.NewMongoReputationStore(mongoClient)
+
+	updated, err := reputationStore.RecomputeReputation(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Reputation recomputed, users updated =>", updated)
+}
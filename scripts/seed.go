@@ -40,7 +40,9 @@ func main() {
 
 	userStore := db.NewMongoUserStore(mongoClient)
 	tagStore := db.NewMongoTagStore(mongoClient)
-	questionStore := db.NewMongoQuestionStore(mongoClient, tagStore, userStore)
+	reputationStore := db.NewMongoReputationStore(mongoClient)
+	answerStore := db.NewMongoAnswerStore(mongoClient, userStore, reputationStore)
+	questionStore := db.NewMongoQuestionStore(mongoClient, tagStore, userStore, answerStore, reputationStore)
 	store := &db.Store{
 		Question: questionStore,
 		User: userStore,
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/fullstack/dev-overflow/db"
+	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Moves the votes embedded in questions and answers into the votes
+// collection, drops the embedded answers and questions arrays and rebuilds
+// every maintained counter. Run it with the API stopped, it is safe to run
+// again.
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Fatal(err)
+	}
+
+	mongoClient, err := mongo.Connect(context.TODO(), options.Client().ApplyURI(os.Getenv("MONGO_DB_URL")))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	voteStore := db.NewMongoVoteStore(mongoClient, db.NewMongoReputationStore(mongoClient))
+
+	migrated, err := voteStore.MigrateVotes(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Votes migrated =>", migrated)
+
+	if err := voteStore.DropMembershipArrays(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Membership arrays dropped")
+
+	if err := voteStore.RecomputeCounters(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Counters recomputed")
+}
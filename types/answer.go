@@ -6,31 +6,55 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	minAnswerLength = 30
+	maxAnswerLength = 30000
+)
+
 type Answer struct {
 	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
 	UserID primitive.ObjectID `bson:"userID" json:"userID"`
-	User *User `bson:"user,omitempty" json:"user,omitempty"`
+	User *PublicUser `bson:"user,omitempty" json:"user,omitempty"`
 	QuestionID primitive.ObjectID `bson:"questionID" json:"questionID"`
 	QuestionDetails *Question `bson:"questionDetails,omitempty" json:"questionDetails,omitempty"`
 	Description string `bson:"content" json:"description"`
-	Upvotes []primitive.ObjectID `bson:"upvotes" json:"upvotes"`
-	Downvotes []primitive.ObjectID `bson:"downvotes" json:"downvotes"`
+	DescriptionHTML string `bson:"-" json:"descriptionHTML,omitempty"`
+	Revision int `bson:"revision,omitempty" json:"revision,omitempty"`
+	Rendered *RenderedBody `bson:"rendered,omitempty" json:"-"`
+	Score int `bson:"score" json:"score"`
+	UpvoteCount int `bson:"upvoteCount" json:"upvoteCount"`
+	DownvoteCount int `bson:"downvoteCount" json:"downvoteCount"`
+	IsAccepted bool `bson:"isAccepted" json:"isAccepted"`
+	LastEditedAt *time.Time `bson:"lastEditedAt,omitempty" json:"lastEditedAt,omitempty"`
+	LastEditedBy primitive.ObjectID `bson:"lastEditedBy,omitempty" json:"lastEditedBy,omitempty"`
+	LastEditor *PublicUser `bson:"lastEditor,omitempty" json:"lastEditor,omitempty"`
+	Comments *CommentList `bson:"-" json:"comments,omitempty"`
+	MyVote *VoteState `bson:"-" json:"myVote,omitempty"`
 	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
 }
 
+// AnswerList is one page of an answer listing.
+type AnswerList struct {
+	Answers []*Answer `json:"answers"`
+	Total int64 `json:"total"`
+	Page int64 `json:"page"`
+	Limit int64 `json:"limit"`
+	HasNext bool `json:"hasNext"`
+}
+
 type CreateAnswerParams struct {
-	UserID primitive.ObjectID `json:"userID"`
+	UserID primitive.ObjectID `json:"-"`
 	QuestionID primitive.ObjectID `json:"questionID"`
 	Description string `json:"description"`
 }
 
-type VoteAnswerParams struct {
-	AnswerID string `json:"answerID"`
-	UserID string `json:"userID"`
-	HasUpvoted bool `json:"hasUpvoted"`
-	HasDownvoted bool `json:"hasDownvoted"`
-}
-
 type DeleteAnswerParams struct {
 	QuestionID primitive.ObjectID `json:"questionID"`
-}
\ No newline at end of file
+}
+
+func (params CreateAnswerParams) Validate() ValidationErrors {
+	return NewValidator().
+		Field("questionID", params.QuestionID, Required()).
+		Field("description", params.Description, Required(), Length(minAnswerLength, maxAnswerLength)).
+		Errors()
+}
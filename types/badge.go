@@ -0,0 +1,254 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type BadgeTier string
+
+const (
+	BadgeGold BadgeTier = "gold"
+	BadgeSilver BadgeTier = "silver"
+	BadgeBronze BadgeTier = "bronze"
+)
+
+// BadgeSource is the collection a badge criteria is evaluated over.
+type BadgeSource string
+
+const (
+	SourceQuestions BadgeSource = "questions"
+	SourceAnswers BadgeSource = "answers"
+	SourceVotes BadgeSource = "votes"
+	SourceInteractions BadgeSource = "interactions"
+)
+
+type CriteriaKind string
+
+const (
+	// CriteriaPost awards the author of a post whose Field reached
+	// Threshold, the post triggers the award.
+	CriteriaPost CriteriaKind = "post"
+	// CriteriaCount awards users with at least Threshold matching
+	// documents, the one reaching the threshold triggers the award.
+	CriteriaCount CriteriaKind = "count"
+	// CriteriaStreak awards users with matching documents on Threshold
+	// consecutive UTC days up to yesterday.
+	CriteriaStreak CriteriaKind = "streak"
+)
+
+// BadgeCriteria declares when a badge is earned. Match holds equality
+// conditions on the source documents and Tag, when set, keeps the posts
+// whose question carries the tag. UserField is who earns the badge and
+// defaults to the author of the document, or of the voted post for votes.
+type BadgeCriteria struct {
+	Kind CriteriaKind
+	Source BadgeSource
+	Field string
+	Threshold int
+	Match map[string]interface{}
+	Tag string
+	UserField string
+}
+
+// Badge is one entry of the badge catalog. Repeatable badges are awarded
+// once per triggering post, the others once per user.
+type Badge struct {
+	ID string `json:"id"`
+	Name string `json:"name"`
+	Description string `json:"description"`
+	Tier BadgeTier `json:"tier"`
+	Repeatable bool `json:"repeatable"`
+	Criteria BadgeCriteria `json:"-"`
+}
+
+// Badges is the badge catalog. Awards reference badges by ID, so IDs must
+// never change.
+var Badges = []*Badge{
+	{
+		ID: "first_accepted_answer",
+		Name: "Helpful",
+		Description: "Wrote an answer that was accepted",
+		Tier: BadgeBronze,
+		Criteria: BadgeCriteria{Kind: CriteriaCount, Source: SourceAnswers, Threshold: 1, Match: map[string]interface{}{"isAccepted": true}},
+	},
+	{
+		ID: "supporter",
+		Name: "Supporter",
+		Description: "Cast a first upvote",
+		Tier: BadgeBronze,
+		Criteria: BadgeCriteria{Kind: CriteriaCount, Source: SourceVotes, Threshold: 1, Match: map[string]interface{}{"vote": VoteUp}, UserField: "userID"},
+	},
+	{
+		ID: "popular_question",
+		Name: "Popular Question",
+		Description: "Asked a question with 1,000 views",
+		Tier: BadgeBronze,
+		Repeatable: true,
+		Criteria: BadgeCriteria{Kind: CriteriaPost, Source: SourceQuestions, Field: "views", Threshold: 1000},
+	},
+	{
+		ID: "notable_question",
+		Name: "Notable Question",
+		Description: "Asked a question with 2,500 views",
+		Tier: BadgeSilver,
+		Repeatable: true,
+		Criteria: BadgeCriteria{Kind: CriteriaPost, Source: SourceQuestions, Field: "views", Threshold: 2500},
+	},
+	{
+		ID: "famous_question",
+		Name: "Famous Question",
+		Description: "Asked a question with 10,000 views",
+		Tier: BadgeGold,
+		Repeatable: true,
+		Criteria: BadgeCriteria{Kind: CriteriaPost, Source: SourceQuestions, Field: "views", Threshold: 10000},
+	},
+	{
+		ID: "good_answer",
+		Name: "Good Answer",
+		Description: "Wrote an answer with a score of 25",
+		Tier: BadgeSilver,
+		Repeatable: true,
+		Criteria: BadgeCriteria{Kind: CriteriaPost, Source: SourceAnswers, Field: "score", Threshold: 25},
+	},
+	{
+		ID: "great_answer",
+		Name: "Great Answer",
+		Description: "Wrote an answer with a score of 100",
+		Tier: BadgeGold,
+		Repeatable: true,
+		Criteria: BadgeCriteria{Kind: CriteriaPost, Source: SourceAnswers, Field: "score", Threshold: 100},
+	},
+	{
+		ID: "go_bronze",
+		Name: "Go",
+		Description: "Earned 100 upvotes on answers in the Go tag",
+		Tier: BadgeBronze,
+		Criteria: tagUpvotes("Go", 100),
+	},
+	{
+		ID: "go_silver",
+		Name: "Go",
+		Description: "Earned 400 upvotes on answers in the Go tag",
+		Tier: BadgeSilver,
+		Criteria: tagUpvotes("Go", 400),
+	},
+	{
+		ID: "go_gold",
+		Name: "Go",
+		Description: "Earned 1,000 upvotes on answers in the Go tag",
+		Tier: BadgeGold,
+		Criteria: tagUpvotes("Go", 1000),
+	},
+	{
+		ID: "enthusiast",
+		Name: "Enthusiast",
+		Description: "Visited the site 30 consecutive days",
+		Tier: BadgeSilver,
+		Criteria: BadgeCriteria{Kind: CriteriaStreak, Source: SourceInteractions, Threshold: 30, Match: map[string]interface{}{"action": InteractionVisit}},
+	},
+	{
+		ID: "fanatic",
+		Name: "Fanatic",
+		Description: "Visited the site 100 consecutive days",
+		Tier: BadgeGold,
+		Criteria: BadgeCriteria{Kind: CriteriaStreak, Source: SourceInteractions, Threshold: 100, Match: map[string]interface{}{"action": InteractionVisit}},
+	},
+}
+
+func tagUpvotes(tag string, threshold int) BadgeCriteria {
+	return BadgeCriteria{
+		Kind: CriteriaCount,
+		Source: SourceVotes,
+		Threshold: threshold,
+		Match: map[string]interface{}{"vote": VoteUp, "targetType": VoteOnAnswer},
+		Tag: tag,
+	}
+}
+
+func BadgeByID(id string) *Badge {
+	for _, badge := range Badges {
+		if badge.ID == id {
+			return badge
+		}
+	}
+	return nil
+}
+
+// BadgeAward is a badge earned by a user. PostID is the post that triggered
+// it, if any. Key makes awarding idempotent: it is unique per badge and user,
+// and per post for repeatable badges.
+type BadgeAward struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Key string `bson:"key" json:"-"`
+	BadgeID string `bson:"badgeID" json:"badgeID"`
+	Badge *Badge `bson:"-" json:"badge,omitempty"`
+	UserID primitive.ObjectID `bson:"userID" json:"userID"`
+	User *PublicUser `bson:"user,omitempty" json:"user,omitempty"`
+	PostID *primitive.ObjectID `bson:"postID,omitempty" json:"postID,omitempty"`
+	PostType string `bson:"postType,omitempty" json:"postType,omitempty"`
+	AwardedAt time.Time `bson:"awardedAt" json:"awardedAt"`
+}
+
+func NewBadgeAward(badge *Badge, userID primitive.ObjectID, postID *primitive.ObjectID, postType string, awardedAt time.Time) *BadgeAward {
+	key := badge.ID + ":" + userID.Hex()
+	if badge.Repeatable && postID != nil {
+		key += ":" + postID.Hex()
+	}
+
+	return &BadgeAward{
+		Key: key,
+		BadgeID: badge.ID,
+		UserID: userID,
+		PostID: postID,
+		PostType: postType,
+		AwardedAt: awardedAt,
+	}
+}
+
+// BadgeSummary is a catalog entry with the number of users holding it.
+type BadgeSummary struct {
+	*Badge
+	HolderCount int64 `json:"holderCount"`
+}
+
+// UserBadges is every badge a user earned, with the count per tier.
+type UserBadges struct {
+	Gold int `json:"gold"`
+	Silver int `json:"silver"`
+	Bronze int `json:"bronze"`
+	Awards []*BadgeAward `json:"awards"`
+}
+
+// BadgeAwardList is one page of the most recent awards.
+type BadgeAwardList struct {
+	Awards []*BadgeAward `json:"awards"`
+	Total int64 `json:"total"`
+	Page int64 `json:"page"`
+	Limit int64 `json:"limit"`
+	HasNext bool `json:"hasNext"`
+}
+
+// NewUserBadges counts the user's awards per tier and fills in their
+// badge.
+func NewUserBadges(awards []*BadgeAward) *UserBadges {
+	badges := &UserBadges{Awards: awards}
+
+	for _, award := range awards {
+		award.Badge = BadgeByID(award.BadgeID)
+		if award.Badge == nil {
+			continue
+		}
+		switch award.Badge.Tier {
+		case BadgeGold:
+			badges.Gold++
+		case BadgeSilver:
+			badges.Silver++
+		case BadgeBronze:
+			badges.Bronze++
+		}
+	}
+
+	return badges
+}
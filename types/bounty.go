@@ -0,0 +1,93 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	MinBountyAmount = 50
+	MaxBountyAmount = 500
+	// MinSponsorReputation is needed to put a bounty on someone else's
+	// question.
+	MinSponsorReputation = 75
+	BountyDuration = 7 * 24 * time.Hour
+)
+
+const (
+	BountyOpen = "open"
+	BountyAwarded = "awarded"
+	BountyExpired = "expired"
+	BountyCancelled = "cancelled"
+)
+
+// Bounty escrows reputation taken from the sponsor until it is awarded to
+// an answer. A bounty that expires without a positively scored answer is
+// not refunded.
+type Bounty struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	QuestionID primitive.ObjectID `bson:"questionID" json:"questionID"`
+	SponsorID primitive.ObjectID `bson:"sponsorID" json:"sponsorID"`
+	Amount int `bson:"amount" json:"amount"`
+	Status string `bson:"status" json:"status"`
+	AnswerID *primitive.ObjectID `bson:"answerID,omitempty" json:"answerID,omitempty"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+	ExpiresAt time.Time `bson:"expiresAt" json:"expiresAt"`
+	ClosedAt *time.Time `bson:"closedAt,omitempty" json:"closedAt,omitempty"`
+}
+
+// QuestionBounty is the open bounty summary stored on the question.
+type QuestionBounty struct {
+	ID primitive.ObjectID `bson:"id" json:"id"`
+	Amount int `bson:"amount" json:"amount"`
+	SponsorID primitive.ObjectID `bson:"sponsorID" json:"sponsorID"`
+	ExpiresAt time.Time `bson:"expiresAt" json:"expiresAt"`
+}
+
+type CreateBountyParams struct {
+	Amount int `json:"amount"`
+}
+
+type AwardBountyParams struct {
+	AnswerID string `json:"answerID"`
+}
+
+func (params CreateBountyParams) Validate() ValidationErrors {
+	return NewValidator().
+		Field("amount", params.Amount, Between(MinBountyAmount, MaxBountyAmount)).
+		Errors()
+}
+
+func (params AwardBountyParams) Validate() ValidationErrors {
+	return NewValidator().
+		Field("answerID", params.AnswerID, Required()).
+		Errors()
+}
+
+func (b *Bounty) Summary() *QuestionBounty {
+	return &QuestionBounty{
+		ID: b.ID,
+		Amount: b.Amount,
+		SponsorID: b.SponsorID,
+		ExpiresAt: b.ExpiresAt,
+	}
+}
+
+// AutoAwardAnswer picks the answer an expired bounty goes to: the highest
+// positively scored answer not written by the sponsor, oldest first on ties.
+func AutoAwardAnswer(bounty *Bounty, answers []*Answer) *Answer {
+	var best *Answer
+
+	for _, answer := range answers {
+		if answer.UserID == bounty.SponsorID || answer.Score <= 0 {
+			continue
+		}
+		if best == nil || answer.Score > best.Score ||
+			(answer.Score == best.Score && answer.CreatedAt.Before(best.CreatedAt)) {
+			best = answer
+		}
+	}
+
+	return best
+}
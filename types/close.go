@@ -0,0 +1,136 @@
+package types
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type CloseReason string
+
+const (
+	CloseDuplicate CloseReason = "duplicate"
+	CloseNeedsDetails CloseReason = "needs_details"
+	CloseOffTopic CloseReason = "off_topic"
+	CloseOpinionBased CloseReason = "opinion_based"
+)
+
+const (
+	// MinCloseVoteReputation is needed to vote to close or reopen questions.
+	MinCloseVoteReputation = 250
+	DefaultCloseVoteThreshold = 3
+)
+
+var closeReasonMessages = map[CloseReason]string{
+	CloseDuplicate: "This question has been asked before and already has an answer.",
+	CloseNeedsDetails: "This question needs details or clarity. It is not currently accepting answers.",
+	CloseOffTopic: "This question is off-topic. It is not currently accepting answers.",
+	CloseOpinionBased: "This question is opinion-based. It is not currently accepting answers.",
+}
+
+type CloseVote struct {
+	UserID primitive.ObjectID `bson:"userID" json:"userID"`
+	Reason CloseReason `bson:"reason" json:"reason"`
+	DuplicateOf *primitive.ObjectID `bson:"duplicateOf,omitempty" json:"duplicateOf,omitempty"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
+
+// QuestionNotice is the banner shown on top of a closed question.
+type QuestionNotice struct {
+	Reason CloseReason `json:"reason"`
+	Message string `json:"message"`
+	ClosedAt time.Time `json:"closedAt"`
+	DuplicateOf *primitive.ObjectID `json:"duplicateOf,omitempty"`
+	DuplicateOfTitle string `json:"duplicateOfTitle,omitempty"`
+}
+
+type CloseQuestionParams struct {
+	Reason CloseReason `json:"reason"`
+	DuplicateOf string `json:"duplicateOf"`
+}
+
+func (r CloseReason) IsValid() bool {
+	_, ok := closeReasonMessages[r]
+	return ok
+}
+
+func (params CloseQuestionParams) Validate() ValidationErrors {
+	v := NewValidator().
+		Field("reason", params.Reason, Required(), Valid(params.Reason.IsValid(), fmt.Sprintf("%q is not a close reason", params.Reason)))
+
+	if params.Reason == CloseDuplicate {
+		v.Field("duplicateOf", params.DuplicateOf, Required())
+	}
+
+	return v.Errors()
+}
+
+func (q *Question) IsClosed() bool {
+	return q.ClosedAt != nil
+}
+
+func (q *Question) HasCloseVote(userID primitive.ObjectID) bool {
+	for _, vote := range q.CloseVotes {
+		if vote.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func (q *Question) HasReopenVote(userID primitive.ObjectID) bool {
+	for _, id := range q.ReopenVotes {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// CloseOutcome picks the reason a question is closed for from its votes:
+// the most voted reason, and for duplicates the most voted original. Ties
+// go to whichever was voted first.
+func CloseOutcome(votes []CloseVote) (CloseReason, *primitive.ObjectID) {
+	var (
+		reasons = map[CloseReason]int{}
+		originals = map[primitive.ObjectID]int{}
+		reason CloseReason
+		original *primitive.ObjectID
+	)
+
+	for _, vote := range votes {
+		reasons[vote.Reason]++
+		if reason == "" || reasons[vote.Reason] > reasons[reason] {
+			reason = vote.Reason
+		}
+		if vote.DuplicateOf != nil {
+			originals[*vote.DuplicateOf]++
+			if original == nil || originals[*vote.DuplicateOf] > originals[*original] {
+				original = vote.DuplicateOf
+			}
+		}
+	}
+
+	if reason != CloseDuplicate {
+		original = nil
+	}
+
+	return reason, original
+}
+
+// NewQuestionNotice returns the banner for a closed question, or nil when it
+// is open.
+func NewQuestionNotice(question *Question, duplicateOfTitle string) *QuestionNotice {
+	if !question.IsClosed() {
+		return nil
+	}
+
+	return &QuestionNotice{
+		Reason: question.CloseReason,
+		Message: closeReasonMessages[question.CloseReason],
+		ClosedAt: *question.ClosedAt,
+		DuplicateOf: question.DuplicateOf,
+		DuplicateOfTitle: duplicateOfTitle,
+	}
+}
@@ -0,0 +1,96 @@
+package types
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	CommentOnQuestion = "question"
+	CommentOnAnswer = "answer"
+)
+
+const (
+	CommentEditWindow = 5 * time.Minute
+	// TopCommentsLimit is how many comments are embedded in a post, the
+	// rest are behind the comments endpoint.
+	TopCommentsLimit = 5
+	minCommentLength = 15
+	maxCommentLength = 600
+)
+
+var mentionPattern = regexp.MustCompile(`@([\p{L}\p{N}_.-]+)`)
+
+type Comment struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	PostID primitive.ObjectID `bson:"postID" json:"postID"`
+	PostType string `bson:"postType" json:"postType"`
+	QuestionID primitive.ObjectID `bson:"questionID" json:"questionID"`
+	UserID primitive.ObjectID `bson:"userID" json:"userID"`
+	User *PublicUser `bson:"user,omitempty" json:"user,omitempty"`
+	Content string `bson:"content" json:"content"`
+	// Upvotes only backs UpvoteCount and is never sent to clients.
+	Upvotes []primitive.ObjectID `bson:"upvotes" json:"-"`
+	UpvoteCount int `bson:"upvoteCount" json:"upvoteCount"`
+	Mentions []primitive.ObjectID `bson:"mentions" json:"mentions"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+	EditedAt *time.Time `bson:"editedAt,omitempty" json:"editedAt,omitempty"`
+}
+
+// CommentList is one page of the comments on a post. Embedded lists only
+// carry the top comments and the total.
+type CommentList struct {
+	Comments []*Comment `json:"comments"`
+	Total int64 `json:"total"`
+	Page int64 `json:"page,omitempty"`
+	Limit int64 `json:"limit,omitempty"`
+	HasNext bool `json:"hasNext"`
+}
+
+type CommentParams struct {
+	Content string `json:"content"`
+}
+
+func (params CommentParams) Validate() ValidationErrors {
+	return NewValidator().
+		Field("content", params.Content, Required(), Length(minCommentLength, maxCommentLength)).
+		Errors()
+}
+
+// CanEdit reports whether the author may still edit the comment.
+func (c *Comment) CanEdit(user *User, now time.Time) bool {
+	return c.UserID == user.ID && now.Sub(c.CreatedAt) <= CommentEditWindow
+}
+
+// ResolveMentions returns the participants mentioned in content. A mention
+// is "@" followed by a participant's name without spaces, or their first
+// name, in any case.
+func ResolveMentions(content string, participants []*PublicUser) []primitive.ObjectID {
+	mentioned := []primitive.ObjectID{}
+	seen := map[primitive.ObjectID]bool{}
+
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		handle := strings.TrimRight(match[1], ".-")
+
+		for _, user := range participants {
+			if user == nil || seen[user.ID] {
+				continue
+			}
+
+			names := strings.Fields(user.Name)
+			if len(names) == 0 {
+				continue
+			}
+
+			if strings.EqualFold(handle, strings.Join(names, "")) || strings.EqualFold(handle, names[0]) {
+				seen[user.ID] = true
+				mentioned = append(mentioned, user.ID)
+			}
+		}
+	}
+
+	return mentioned
+}
@@ -0,0 +1,41 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestResolveMentions(t *testing.T) {
+	var (
+		jane = &PublicUser{ID: primitive.NewObjectID(), Name: "Jane Doe"}
+		john = &PublicUser{ID: primitive.NewObjectID(), Name: "John"}
+		nameless = &PublicUser{ID: primitive.NewObjectID(), Name: " "}
+		participants = []*PublicUser{jane, john, nameless, nil}
+	)
+
+	tests := []struct {
+		name string
+		content string
+		want []primitive.ObjectID
+	}{
+		{name: "no mention", content: "thanks for the answer", want: []primitive.ObjectID{}},
+		{name: "full name", content: "@JaneDoe see above", want: []primitive.ObjectID{jane.ID}},
+		{name: "first name", content: "@jane see above", want: []primitive.ObjectID{jane.ID}},
+		{name: "trailing punctuation", content: "thanks @John.", want: []primitive.ObjectID{john.ID}},
+		{name: "in order of mention", content: "@john and @jane", want: []primitive.ObjectID{john.ID, jane.ID}},
+		{name: "mentioned twice", content: "@jane @JaneDoe", want: []primitive.ObjectID{jane.ID}},
+		{name: "not a participant", content: "@alice", want: []primitive.ObjectID{}},
+		{name: "partial name", content: "@Ja", want: []primitive.ObjectID{}},
+		{name: "email is not a mention", content: "mail jane@example.com", want: []primitive.ObjectID{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResolveMentions(tt.content, participants); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ResolveMentions(%q) = %v, want %v", tt.content, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type FraudPattern string
+
+const (
+	// FraudSerialVoting is one user voting on many posts of the same user
+	// in a short window.
+	FraudSerialVoting FraudPattern = "serial_voting"
+	// FraudVotingRing is a small group of users upvoting each other.
+	FraudVotingRing FraudPattern = "voting_ring"
+)
+
+const (
+	// AuditPending audits are recorded before their votes are reversed,
+	// so a detection cut short is finished by the next run.
+	AuditPending = "pending"
+	// AuditReversed audits are waiting for a moderator, their votes are
+	// reversed meanwhile.
+	AuditReversed = "reversed"
+	AuditConfirmed = "confirmed"
+	// AuditRestoring audits were overridden by a moderator and their votes
+	// are being cast again, AuditRestored once they all are.
+	AuditRestoring = "restoring"
+	AuditRestored = "restored"
+)
+
+// FraudRules are the thresholds of the vote fraud detection.
+type FraudRules struct {
+	// SerialWindow and SerialMinVotes flag a voter casting at least
+	// SerialMinVotes votes on one user's posts within SerialWindow.
+	SerialWindow time.Duration
+	SerialMinVotes int
+	// Users upvoting each other at least RingMinVotes times within
+	// RingLookback form a ring, unless more than RingMaxSize users are
+	// linked that way.
+	RingLookback time.Duration
+	RingMinVotes int
+	RingMaxSize int
+}
+
+var DefaultFraudRules = FraudRules{
+	SerialWindow: time.Hour,
+	SerialMinVotes: 5,
+	RingLookback: 30 * 24 * time.Hour,
+	RingMinVotes: 5,
+	RingMaxSize: 4,
+}
+
+// VotePair counts the votes one user cast on another user's posts.
+type VotePair struct {
+	VoterID primitive.ObjectID `bson:"voterID" json:"voterID"`
+	OwnerID primitive.ObjectID `bson:"ownerID" json:"ownerID"`
+	Count int `bson:"count" json:"count"`
+}
+
+// VoteAudit records votes reversed by the fraud detection. The reversed
+// votes are kept so a moderator overriding the detection can restore them.
+type VoteAudit struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Pattern FraudPattern `bson:"pattern" json:"pattern"`
+	VoterIDs []primitive.ObjectID `bson:"voterIDs" json:"voterIDs"`
+	OwnerIDs []primitive.ObjectID `bson:"ownerIDs" json:"ownerIDs"`
+	Votes []*Vote `bson:"votes" json:"votes"`
+	// Pending holds the ids of the votes still to be reversed or restored.
+	Pending []primitive.ObjectID `bson:"pending" json:"-"`
+	Status string `bson:"status" json:"status"`
+	ReviewedBy *primitive.ObjectID `bson:"reviewedBy,omitempty" json:"reviewedBy,omitempty"`
+	ReviewedAt *time.Time `bson:"reviewedAt,omitempty" json:"reviewedAt,omitempty"`
+	ReviewNote string `bson:"reviewNote,omitempty" json:"reviewNote,omitempty"`
+	DetectedAt time.Time `bson:"detectedAt" json:"detectedAt"`
+}
+
+// VoteAuditList is one page of vote audits.
+type VoteAuditList struct {
+	Audits []*VoteAudit `json:"audits"`
+	Total int64 `json:"total"`
+	Page int64 `json:"page"`
+	Limit int64 `json:"limit"`
+	HasNext bool `json:"hasNext"`
+}
+
+type AuditDecision string
+
+const (
+	// DecisionConfirm keeps the votes reversed.
+	DecisionConfirm AuditDecision = "confirm"
+	// DecisionRestore overrides the detection and casts the votes again.
+	DecisionRestore AuditDecision = "restore"
+)
+
+const maxReviewNoteLength = 500
+
+type ReviewVoteAuditParams struct {
+	Decision AuditDecision `json:"decision"`
+	Note string `json:"note"`
+}
+
+func (d AuditDecision) IsValid() bool {
+	return d == DecisionConfirm || d == DecisionRestore
+}
+
+// Status is the audit status a decision moves the audit to.
+func (d AuditDecision) Status() string {
+	if d == DecisionRestore {
+		return AuditRestoring
+	}
+	return AuditConfirmed
+}
+
+func (params ReviewVoteAuditParams) Validate() ValidationErrors {
+	return NewValidator().
+		Field("decision", params.Decision, Required(), Valid(params.Decision.IsValid(), "must be confirm or restore")).
+		Field("note", params.Note, MaxLength(maxReviewNoteLength)).
+		Errors()
+}
+
+// SerialVotes returns the votes of one voter on one owner's posts that fall
+// in a window of the given length holding at least min votes. votes must be
+// sorted by UpdatedAt.
+func SerialVotes(votes []*Vote, window time.Duration, min int) []*Vote {
+	flagged := make([]bool, len(votes))
+
+	start := 0
+	for end := range votes {
+		for votes[end].UpdatedAt.Sub(votes[start].UpdatedAt) > window {
+			start++
+		}
+		if end-start+1 >= min {
+			for i := start; i <= end; i++ {
+				flagged[i] = true
+			}
+		}
+	}
+
+	serial := []*Vote{}
+	for i, vote := range votes {
+		if flagged[i] {
+			serial = append(serial, vote)
+		}
+	}
+
+	return serial
+}
+
+// VotingRings groups users who upvoted each other, each pair in both
+// directions, into rings of at most maxSize users. Larger groups look like
+// an active community rather than a ring and are left alone.
+func VotingRings(pairs []*VotePair, maxSize int) [][]primitive.ObjectID {
+	type edge struct{ from, to primitive.ObjectID }
+
+	voted := make(map[edge]bool, len(pairs))
+	for _, pair := range pairs {
+		voted[edge{pair.VoterID, pair.OwnerID}] = true
+	}
+
+	var (
+		order []primitive.ObjectID
+		mutual = map[primitive.ObjectID][]primitive.ObjectID{}
+	)
+	for _, pair := range pairs {
+		if !voted[edge{pair.OwnerID, pair.VoterID}] || pair.VoterID == pair.OwnerID {
+			continue
+		}
+		if _, seen := mutual[pair.VoterID]; !seen {
+			order = append(order, pair.VoterID)
+		}
+		mutual[pair.VoterID] = append(mutual[pair.VoterID], pair.OwnerID)
+	}
+
+	var (
+		rings [][]primitive.ObjectID
+		visited = map[primitive.ObjectID]bool{}
+	)
+	for _, start := range order {
+		if visited[start] {
+			continue
+		}
+
+		ring := []primitive.ObjectID{start}
+		visited[start] = true
+		for i := 0; i < len(ring); i++ {
+			for _, next := range mutual[ring[i]] {
+				if !visited[next] {
+					visited[next] = true
+					ring = append(ring, next)
+				}
+			}
+		}
+
+		if len(ring) <= maxSize {
+			rings = append(rings, ring)
+		}
+	}
+
+	return rings
+}
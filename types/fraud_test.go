@@ -0,0 +1,96 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSerialVotes(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	votesAt := func(minutes ...int) []*Vote {
+		votes := make([]*Vote, len(minutes))
+		for i, m := range minutes {
+			votes[i] = &Vote{ID: primitive.NewObjectID(), UpdatedAt: start.Add(time.Duration(m) * time.Minute)}
+		}
+		return votes
+	}
+
+	tests := []struct {
+		name string
+		votes []*Vote
+		min int
+		want []int
+	}{
+		{name: "no votes", votes: votesAt(), min: 3, want: []int{}},
+		{name: "too few", votes: votesAt(0, 10), min: 3, want: []int{}},
+		{name: "burst", votes: votesAt(0, 10, 20), min: 3, want: []int{0, 1, 2}},
+		{name: "burst then a late vote", votes: votesAt(0, 10, 20, 180), min: 3, want: []int{0, 1, 2}},
+		{name: "spread out", votes: votesAt(0, 50, 100, 150), min: 3, want: []int{}},
+		{name: "window edge is inclusive", votes: votesAt(0, 30, 60), min: 3, want: []int{0, 1, 2}},
+		{name: "sliding window", votes: votesAt(0, 40, 70, 90, 200), min: 3, want: []int{1, 2, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := []*Vote{}
+			for _, i := range tt.want {
+				want = append(want, tt.votes[i])
+			}
+
+			if got := SerialVotes(tt.votes, time.Hour, tt.min); !reflect.DeepEqual(got, want) {
+				t.Fatalf("SerialVotes() returned %d votes, want %d", len(got), len(want))
+			}
+		})
+	}
+}
+
+func TestVotingRings(t *testing.T) {
+	ids := make([]primitive.ObjectID, 5)
+	for i := range ids {
+		ids[i] = primitive.NewObjectID()
+	}
+	a, b, c, d, e := ids[0], ids[1], ids[2], ids[3], ids[4]
+
+	pair := func(voter, owner primitive.ObjectID) *VotePair {
+		return &VotePair{VoterID: voter, OwnerID: owner, Count: 5}
+	}
+
+	tests := []struct {
+		name string
+		pairs []*VotePair
+		want [][]primitive.ObjectID
+	}{
+		{name: "no pairs", pairs: nil, want: nil},
+		{name: "one way", pairs: []*VotePair{pair(a, b)}, want: nil},
+		{name: "mutual pair", pairs: []*VotePair{pair(a, b), pair(b, a)}, want: [][]primitive.ObjectID{{a, b}}},
+		{name: "self votes ignored", pairs: []*VotePair{pair(a, a)}, want: nil},
+		{name: "one way edge left out", pairs: []*VotePair{pair(a, b), pair(b, a), pair(c, a)}, want: [][]primitive.ObjectID{{a, b}}},
+		{
+			name: "separate rings",
+			pairs: []*VotePair{pair(a, b), pair(b, a), pair(c, d), pair(d, c)},
+			want: [][]primitive.ObjectID{{a, b}, {c, d}},
+		},
+		{
+			name: "chain joins one ring",
+			pairs: []*VotePair{pair(a, b), pair(b, a), pair(b, c), pair(c, b)},
+			want: [][]primitive.ObjectID{{a, b, c}},
+		},
+		{
+			name: "too large to be a ring",
+			pairs: []*VotePair{pair(a, b), pair(b, a), pair(b, c), pair(c, b), pair(c, d), pair(d, c), pair(d, e), pair(e, d)},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VotingRings(tt.pairs, 4); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("VotingRings() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
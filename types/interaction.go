@@ -6,6 +6,17 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	InteractionView = "view"
+	InteractionAcceptAnswer = "accept_answer"
+	InteractionUnacceptAnswer = "unaccept_answer"
+	InteractionAnswerAccepted = "answer_accepted"
+	InteractionMention = "mention"
+	// InteractionVisit is recorded on a user's first request of each UTC
+	// day.
+	InteractionVisit = "visit"
+)
+
 type Interaction struct {
 	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
 	UserID primitive.ObjectID `bson:"userID" json:"userID"`
@@ -17,6 +28,12 @@ type Interaction struct {
 }
 
 type ViewQuestionParams struct {
-	UserID string `json:"userID"`
+	UserID string `json:"-"`
 	QuestionID string `json:"questionID"`
-}
\ No newline at end of file
+}
+
+func (params ViewQuestionParams) Validate() ValidationErrors {
+	return NewValidator().
+		Field("questionID", params.QuestionID, Required()).
+		Errors()
+}
@@ -0,0 +1,189 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// Privilege is something a user earns by reaching a reputation.
+type Privilege string
+
+const (
+	PrivVoteUp Privilege = "vote_up"
+	PrivComment Privilege = "comment"
+	PrivOfferBounty Privilege = "offer_bounty"
+	PrivVoteDown Privilege = "vote_down"
+	PrivCloseVote Privilege = "close_vote"
+	PrivCreateTag Privilege = "create_tag"
+	PrivEditPosts Privilege = "edit_posts"
+)
+
+// PrivilegeRule is one row of the privilege table.
+type PrivilegeRule struct {
+	Privilege Privilege `json:"privilege"`
+	MinReputation int `json:"minReputation"`
+	Description string `json:"description"`
+}
+
+// Privileges is the privilege table, in the order they are earned.
+var Privileges = []PrivilegeRule{
+	{PrivVoteUp, 15, "Vote up"},
+	{PrivComment, 50, "Comment on other users' posts"},
+	{PrivOfferBounty, MinSponsorReputation, "Offer a bounty on other users' questions"},
+	{PrivVoteDown, 125, "Vote down"},
+	{PrivCloseVote, MinCloseVoteReputation, "Vote to close or reopen questions"},
+	{PrivCreateTag, 300, "Create new tags"},
+	{PrivEditPosts, MinEditAnyPostReputation, "Edit other users' posts"},
+}
+
+func PrivilegeRuleOf(priv Privilege) PrivilegeRule {
+	for _, rule := range Privileges {
+		if rule.Privilege == priv {
+			return rule
+		}
+	}
+	return PrivilegeRule{Privilege: priv}
+}
+
+// Message explains to user why they lack the privilege.
+func (rule PrivilegeRule) Message(user *User) string {
+	return fmt.Sprintf("%s requires the %s privilege, earned at %d reputation, you have %d", rule.Description, rule.Privilege, rule.MinReputation, user.Reputation)
+}
+
+// HasPrivilege reports whether the user's reputation earned priv. Anyone
+// who can moderate posts has every privilege.
+func (u *User) HasPrivilege(priv Privilege) bool {
+	return u.Can(PermModeratePosts) || u.Reputation >= PrivilegeRuleOf(priv).MinReputation
+}
+
+// VotePrivilege is the privilege needed to cast vote, none to clear one.
+func VotePrivilege(vote VoteState) (Privilege, bool) {
+	switch vote {
+	case VoteUp:
+		return PrivVoteUp, true
+	case VoteDown:
+		return PrivVoteDown, true
+	}
+	return "", false
+}
+
+// QuotaKind is an action limited to a number of times a day.
+type QuotaKind string
+
+const (
+	QuotaVotes QuotaKind = "votes"
+	QuotaQuestions QuotaKind = "questions"
+	QuotaAnswers QuotaKind = "answers"
+	QuotaComments QuotaKind = "comments"
+)
+
+// NewUserReputation is the reputation below which the stricter new user
+// quotas apply.
+const NewUserReputation = 50
+
+// QuotaRule is the daily limit of one kind of action.
+type QuotaRule struct {
+	Kind QuotaKind
+	Limit int
+	NewUserLimit int
+}
+
+var QuotaRules = []QuotaRule{
+	{QuotaVotes, 40, 20},
+	{QuotaQuestions, 20, 3},
+	{QuotaAnswers, 30, 10},
+	{QuotaComments, 50, 10},
+}
+
+// DailyQuota returns how many times a day the user may do kind. Anyone who
+// can moderate posts is not limited and gets -1.
+func (u *User) DailyQuota(kind QuotaKind) int {
+	if u.Can(PermModeratePosts) {
+		return -1
+	}
+
+	for _, rule := range QuotaRules {
+		if rule.Kind != kind {
+			continue
+		}
+		if u.Reputation < NewUserReputation {
+			return rule.NewUserLimit
+		}
+		return rule.Limit
+	}
+
+	return -1
+}
+
+// QuotaDay is the UTC day quotas are counted in, quotas reset at midnight
+// UTC.
+func QuotaDay(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
+
+// QuotaReset returns when the quotas counted at t reset.
+func QuotaReset(t time.Time) time.Time {
+	return StartOfDay(t).AddDate(0, 0, 1)
+}
+
+// StartOfDay returns midnight UTC of the day of t.
+func StartOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+type PrivilegeStatus struct {
+	PrivilegeRule
+	Granted bool `json:"granted"`
+}
+
+// QuotaStatus is what is left of a daily quota. Limit and Remaining are -1
+// for unlimited users.
+type QuotaStatus struct {
+	Kind QuotaKind `json:"kind"`
+	Limit int `json:"limit"`
+	Used int `json:"used"`
+	Remaining int `json:"remaining"`
+	ResetsAt time.Time `json:"resetsAt"`
+}
+
+// UserPrivileges is the caller's standing against the privilege table and
+// the daily quotas.
+type UserPrivileges struct {
+	Reputation int `json:"reputation"`
+	Privileges []PrivilegeStatus `json:"privileges"`
+	Quotas []QuotaStatus `json:"quotas"`
+}
+
+// NewUserPrivileges reports the user's privileges and quotas given what
+// they used today.
+func NewUserPrivileges(user *User, used map[QuotaKind]int, now time.Time) *UserPrivileges {
+	privileges := &UserPrivileges{
+		Reputation: user.Reputation,
+		Privileges: make([]PrivilegeStatus, len(Privileges)),
+		Quotas: make([]QuotaStatus, len(QuotaRules)),
+	}
+
+	for i, rule := range Privileges {
+		privileges.Privileges[i] = PrivilegeStatus{PrivilegeRule: rule, Granted: user.HasPrivilege(rule.Privilege)}
+	}
+
+	for i, rule := range QuotaRules {
+		limit, remaining := user.DailyQuota(rule.Kind), -1
+		if limit >= 0 {
+			remaining = limit - used[rule.Kind]
+			if remaining < 0 {
+				remaining = 0
+			}
+		}
+		privileges.Quotas[i] = QuotaStatus{
+			Kind: rule.Kind,
+			Limit: limit,
+			Used: used[rule.Kind],
+			Remaining: remaining,
+			ResetsAt: QuotaReset(now),
+		}
+	}
+
+	return privileges
+}
@@ -0,0 +1,73 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewUserPrivilegesQuotas(t *testing.T) {
+	now := time.Date(2024, 3, 10, 15, 30, 0, 0, time.UTC)
+	reset := time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		user *User
+		used int
+		limit int
+		remaining int
+	}{
+		{name: "new user", user: &User{Reputation: NewUserReputation - 1}, used: 5, limit: 20, remaining: 15},
+		{name: "new user over the limit", user: &User{Reputation: 1}, used: 25, limit: 20, remaining: 0},
+		{name: "established user", user: &User{Reputation: NewUserReputation}, used: 5, limit: 40, remaining: 35},
+		{name: "established user used up", user: &User{Reputation: 500}, used: 40, limit: 40, remaining: 0},
+		{name: "moderator", user: &User{Role: RoleModerator}, used: 100, limit: -1, remaining: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			privileges := NewUserPrivileges(tt.user, map[QuotaKind]int{QuotaVotes: tt.used}, now)
+
+			var votes *QuotaStatus
+			for i := range privileges.Quotas {
+				if privileges.Quotas[i].Kind == QuotaVotes {
+					votes = &privileges.Quotas[i]
+				}
+			}
+			if votes == nil {
+				t.Fatal("no votes quota")
+			}
+
+			if votes.Limit != tt.limit || votes.Remaining != tt.remaining || votes.Used != tt.used {
+				t.Fatalf("votes quota = %d/%d used %d, want %d/%d used %d", votes.Remaining, votes.Limit, votes.Used, tt.remaining, tt.limit, tt.used)
+			}
+			if !votes.ResetsAt.Equal(reset) {
+				t.Fatalf("resets at %s, want %s", votes.ResetsAt, reset)
+			}
+		})
+	}
+}
+
+func TestNewUserPrivilegesGranted(t *testing.T) {
+	tests := []struct {
+		name string
+		user *User
+		granted map[Privilege]bool
+	}{
+		{name: "new user", user: &User{Reputation: 1}, granted: map[Privilege]bool{PrivVoteUp: false, PrivVoteDown: false}},
+		{name: "at the threshold", user: &User{Reputation: 15}, granted: map[Privilege]bool{PrivVoteUp: true, PrivVoteDown: false}},
+		{name: "trusted user", user: &User{Reputation: 300}, granted: map[Privilege]bool{PrivVoteDown: true, PrivCreateTag: true, PrivEditPosts: false}},
+		{name: "moderator", user: &User{Role: RoleModerator}, granted: map[Privilege]bool{PrivOfferBounty: true, PrivEditPosts: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			privileges := NewUserPrivileges(tt.user, nil, time.Now())
+
+			for _, status := range privileges.Privileges {
+				if want, ok := tt.granted[status.Privilege]; ok && status.Granted != want {
+					t.Fatalf("%s granted = %v, want %v", status.Privilege, status.Granted, want)
+				}
+			}
+		})
+	}
+}
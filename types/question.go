@@ -1,7 +1,6 @@
 package types
 
 import (
-	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -9,7 +8,9 @@ import (
 
 const (
 	minTitleLength = 20
+	maxTitleLength = 150
 	minDescriptionLength = 100
+	maxDescriptionLength = 30000
 	maxTagsLength = 3
 )
 
@@ -17,60 +18,58 @@ type Question struct {
 	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
 	Title string `bson:"title" json:"title"`
 	Description string `bson:"description" json:"description"`
+	DescriptionHTML string `bson:"-" json:"descriptionHTML,omitempty"`
+	Revision int `bson:"revision,omitempty" json:"revision,omitempty"`
+	Rendered *RenderedBody `bson:"rendered,omitempty" json:"-"`
 	UserID primitive.ObjectID `bson:"userID,omitempty" json:"userID,omitempty"`
-	User *User `bson:"user,omitempty" json:"user,omitempty"`
+	User *PublicUser `bson:"user,omitempty" json:"user,omitempty"`
 	Tags []primitive.ObjectID `bson:"tags" json:"tags"`
 	TagDetails []*Tag `bson:"tagDetails,omitempty" json:"tagDetails,omitempty"`
 	Views int `bson:"views" json:"views"`
-	Upvotes []primitive.ObjectID `bson:"upvotes" json:"upvotes"`
-	Downvotes []primitive.ObjectID `bson:"downvotes" json:"downvotes"`
-	Answers []primitive.ObjectID `bson:"answers" json:"answers"`
+	Score int `bson:"score" json:"score"`
+	UpvoteCount int `bson:"upvoteCount" json:"upvoteCount"`
+	DownvoteCount int `bson:"downvoteCount" json:"downvoteCount"`
+	AnswerCount int `bson:"answerCount" json:"answerCount"`
+	AcceptedAnswerID *primitive.ObjectID `bson:"acceptedAnswerID,omitempty" json:"acceptedAnswerID,omitempty"`
+	Bounty *QuestionBounty `bson:"bounty,omitempty" json:"bounty,omitempty"`
+	ClosedAt *time.Time `bson:"closedAt,omitempty" json:"closedAt,omitempty"`
+	CloseReason CloseReason `bson:"closeReason,omitempty" json:"closeReason,omitempty"`
+	ClosedBy []primitive.ObjectID `bson:"closedBy,omitempty" json:"closedBy,omitempty"`
+	DuplicateOf *primitive.ObjectID `bson:"duplicateOf,omitempty" json:"duplicateOf,omitempty"`
+	Duplicates []primitive.ObjectID `bson:"duplicates,omitempty" json:"duplicates,omitempty"`
+	CloseVotes []CloseVote `bson:"closeVotes,omitempty" json:"closeVotes,omitempty"`
+	ReopenVotes []primitive.ObjectID `bson:"reopenVotes,omitempty" json:"reopenVotes,omitempty"`
+	Notice *QuestionNotice `bson:"-" json:"notice,omitempty"`
+	Comments *CommentList `bson:"-" json:"comments,omitempty"`
+	MyVote *VoteState `bson:"-" json:"myVote,omitempty"`
 	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+	LastEditedAt *time.Time `bson:"lastEditedAt,omitempty" json:"lastEditedAt,omitempty"`
+	LastEditedBy primitive.ObjectID `bson:"lastEditedBy,omitempty" json:"lastEditedBy,omitempty"`
+}
+
+// QuestionList is one page of a question listing.
+type QuestionList struct {
+	Questions []*Question `json:"questions"`
+	Total int64 `json:"total"`
+	Page int64 `json:"page"`
+	Limit int64 `json:"limit"`
+	HasNext bool `json:"hasNext"`
+	NextCursor string `json:"nextCursor,omitempty"`
 }
 
 type AskQuestionParams struct {
 	Title string `json:"title"`
 	Description string `json:"description"`
-	ClerkID string `json:"clerkID"`
 	Tags []string `json:"tags"`
+	// NotDuplicate acknowledges that the near-identical questions found
+	// while asking were checked.
+	NotDuplicate bool `json:"notDuplicate"`
 }
 
-type UpdateQuestionAnswersParams struct {
-	QuestionID string `json:"questionID"`
-	Answers primitive.ObjectID `json:"answers"`
-}
-
-type DeleteQuestionParams struct {
-	UserID string `json:"userID"`
-}
-
-type QuestionVoteParams struct {
-	QuestionID string `json:"questionID"`
-	UserID string `json:"userID"`
-	HasUpvoted bool `json:"hasUpvoted"`
-	HasDownvoted bool `json:"hasDownvoted"`
+func (params AskQuestionParams) Validate() ValidationErrors {
+	return NewValidator().
+		Field("title", params.Title, Required(), Length(minTitleLength, maxTitleLength)).
+		Field("description", params.Description, Required(), Length(minDescriptionLength, maxDescriptionLength)).
+		Field("tags", params.Tags, Required(), MaxItems(maxTagsLength)).
+		Errors()
 }
-
-// type SavedQuestionQueryParams struct {
-// 	Page int64
-// 	Limit int64
-// 	SearchQuery string
-// }
-
-func (params AskQuestionParams) Validate() map[string]string {
-	errors := map[string]string{}
-
-	if len(params.Title) < minTitleLength {
-		errors["title"] = fmt.Sprintf("Title should be at least %d characters", minTitleLength)
-	}
-
-	if len(params.Description) < minDescriptionLength {
-		errors["description"] = fmt.Sprintf("Description must be at least %d characters", minDescriptionLength)
-	}
-
-	if len(params.Tags) > maxTagsLength {
-		errors["tags"] = fmt.Sprintf("Tag must be at most %d items", maxTagsLength)
-	}
-
-	return errors
-}
\ No newline at end of file
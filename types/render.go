@@ -0,0 +1,22 @@
+package types
+
+// MinFollowedLinkReputation is the reputation from which an author's links
+// are no longer marked rel="nofollow ugc".
+const MinFollowedLinkReputation = 100
+
+// RenderedBody caches the sanitized HTML of a post for one revision.
+type RenderedBody struct {
+	Revision int `bson:"revision"`
+	NoFollow bool `bson:"noFollow"`
+	HTML string `bson:"html"`
+}
+
+func (r *RenderedBody) IsCurrent(revision int, nofollow bool) bool {
+	return r != nil && r.Revision == revision && r.NoFollow == nofollow
+}
+
+// LinksNeedNoFollow reports whether links written by author are marked
+// nofollow. Unknown authors are not trusted.
+func LinksNeedNoFollow(author *PublicUser) bool {
+	return author == nil || author.Reputation < MinFollowedLinkReputation
+}
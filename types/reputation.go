@@ -0,0 +1,127 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ReputationReason string
+
+const (
+	ReasonQuestionUpvoted ReputationReason = "question_upvoted"
+	ReasonQuestionDownvoted ReputationReason = "question_downvoted"
+	ReasonAnswerUpvoted ReputationReason = "answer_upvoted"
+	ReasonAnswerDownvoted ReputationReason = "answer_downvoted"
+	ReasonDownvotedAnswer ReputationReason = "downvoted_answer"
+	ReasonAnswerAccepted ReputationReason = "answer_accepted"
+	ReasonAcceptedAnswer ReputationReason = "accepted_answer"
+	ReasonBountyOffered ReputationReason = "bounty_offered"
+	ReasonBountyAwarded ReputationReason = "bounty_awarded"
+)
+
+// ReputationPoints is what each reason is worth to the user it is recorded
+// for. Bounty events carry the bounty amount instead.
+var ReputationPoints = map[ReputationReason]int{
+	ReasonQuestionUpvoted: 10,
+	ReasonQuestionDownvoted: -2,
+	ReasonAnswerUpvoted: 10,
+	ReasonAnswerDownvoted: -2,
+	ReasonDownvotedAnswer: -1,
+	ReasonAnswerAccepted: 15,
+	ReasonAcceptedAnswer: 2,
+}
+
+// ReputationEvent is an immutable ledger entry. Undoing an event records a
+// new one with the opposite points that references it through Reverses.
+type ReputationEvent struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID primitive.ObjectID `bson:"userID" json:"userID"`
+	ActorID primitive.ObjectID `bson:"actorID" json:"actorID"`
+	Reason ReputationReason `bson:"reason" json:"reason"`
+	Points int `bson:"points" json:"points"`
+	QuestionID primitive.ObjectID `bson:"questionID" json:"questionID"`
+	AnswerID *primitive.ObjectID `bson:"answerID,omitempty" json:"answerID,omitempty"`
+	Reverses *primitive.ObjectID `bson:"reverses,omitempty" json:"reverses,omitempty"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
+
+type ReputationHistory struct {
+	Reputation int `json:"reputation"`
+	Events []*ReputationEvent `json:"events"`
+	Total int64 `json:"total"`
+	Page int64 `json:"page"`
+	Limit int64 `json:"limit"`
+	HasNext bool `json:"hasNext"`
+}
+
+// PostRef identifies the question or answer a reputation change is about.
+// AnswerID is nil for questions.
+type PostRef struct {
+	QuestionID primitive.ObjectID
+	AnswerID *primitive.ObjectID
+	OwnerID primitive.ObjectID
+}
+
+func NewReputationEvent(userID, actorID primitive.ObjectID, reason ReputationReason, post PostRef) *ReputationEvent {
+	return &ReputationEvent{
+		UserID: userID,
+		ActorID: actorID,
+		Reason: reason,
+		Points: ReputationPoints[reason],
+		QuestionID: post.QuestionID,
+		AnswerID: post.AnswerID,
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+func NewBountyReputationEvent(userID primitive.ObjectID, reason ReputationReason, bounty *Bounty) *ReputationEvent {
+	points := bounty.Amount
+	if reason == ReasonBountyOffered {
+		points = -bounty.Amount
+	}
+
+	return &ReputationEvent{
+		UserID: userID,
+		ActorID: bounty.SponsorID,
+		Reason: reason,
+		Points: points,
+		QuestionID: bounty.QuestionID,
+		AnswerID: bounty.AnswerID,
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+// VoteReasons returns the reasons a vote on post records.
+func VoteReasons(post PostRef, vote VoteState) []ReputationReason {
+	isAnswer := post.AnswerID != nil
+
+	switch {
+	case vote == VoteUp && isAnswer:
+		return []ReputationReason{ReasonAnswerUpvoted}
+	case vote == VoteUp:
+		return []ReputationReason{ReasonQuestionUpvoted}
+	case vote == VoteDown && isAnswer:
+		return []ReputationReason{ReasonAnswerDownvoted, ReasonDownvotedAnswer}
+	case vote == VoteDown:
+		return []ReputationReason{ReasonQuestionDownvoted}
+	}
+
+	return nil
+}
+
+// VoteReputationEvents returns the events for voterID casting vote on post.
+// Downvoting an answer also costs the voter.
+func VoteReputationEvents(post PostRef, voterID primitive.ObjectID, vote VoteState) []*ReputationEvent {
+	events := []*ReputationEvent{}
+
+	for _, reason := range VoteReasons(post, vote) {
+		userID := post.OwnerID
+		if reason == ReasonDownvotedAnswer {
+			userID = voterID
+		}
+		events = append(events, NewReputationEvent(userID, voterID, reason, post))
+	}
+
+	return events
+}
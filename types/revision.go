@@ -0,0 +1,131 @@
+package types
+
+import (
+	"time"
+
+	"github.com/fullstack/dev-overflow/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const maxEditSummaryLength = 300
+
+// QuestionRevision is a full snapshot of a question after an edit. Revision
+// 1 is the question as it was first asked.
+type QuestionRevision struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	QuestionID primitive.ObjectID `bson:"questionID" json:"questionID"`
+	Revision int `bson:"revision" json:"revision"`
+	Title string `bson:"title" json:"title"`
+	Description string `bson:"description" json:"description"`
+	Tags []primitive.ObjectID `bson:"tags" json:"tags"`
+	TagNames []string `bson:"tagNames" json:"tagNames"`
+	EditorID primitive.ObjectID `bson:"editorID" json:"editorID"`
+	EditSummary string `bson:"editSummary" json:"editSummary"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
+
+// AnswerRevision is a full snapshot of an answer after an edit. Revision 1
+// is the answer as it was first posted.
+type AnswerRevision struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	AnswerID primitive.ObjectID `bson:"answerID" json:"answerID"`
+	QuestionID primitive.ObjectID `bson:"questionID" json:"questionID"`
+	Revision int `bson:"revision" json:"revision"`
+	Description string `bson:"content" json:"description"`
+	EditorID primitive.ObjectID `bson:"editorID" json:"editorID"`
+	EditSummary string `bson:"editSummary" json:"editSummary"`
+	RollbackOf int `bson:"rollbackOf,omitempty" json:"rollbackOf,omitempty"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
+
+// NextRevision returns the revision an edit of a post at current creates.
+// Posts that were never edited are at revision 1 but store no revision.
+func NextRevision(current int) int {
+	if current < 1 {
+		current = 1
+	}
+	return current + 1
+}
+
+type EditQuestionParams struct {
+	Title string `json:"title"`
+	Description string `json:"description"`
+	Tags []string `json:"tags"`
+	EditSummary string `json:"editSummary"`
+}
+
+type EditAnswerParams struct {
+	Description string `json:"description"`
+	EditSummary string `json:"editSummary"`
+}
+
+type RollbackAnswerParams struct {
+	Revision int `json:"revision"`
+	EditSummary string `json:"editSummary"`
+}
+
+type QuestionRevisionDiff struct {
+	QuestionID primitive.ObjectID `json:"questionID"`
+	From int `json:"from"`
+	To int `json:"to"`
+	Title []utils.DiffLine `json:"title"`
+	Description []utils.DiffLine `json:"description"`
+	TagsAdded []string `json:"tagsAdded"`
+	TagsRemoved []string `json:"tagsRemoved"`
+}
+
+// Validate applies the same rules as asking a question.
+func (params EditQuestionParams) Validate() ValidationErrors {
+	return NewValidator().
+		Merge(AskQuestionParams{
+			Title: params.Title,
+			Description: params.Description,
+			Tags: params.Tags,
+		}.Validate()).
+		Field("editSummary", params.EditSummary, MaxLength(maxEditSummaryLength)).
+		Errors()
+}
+
+func (params EditAnswerParams) Validate() ValidationErrors {
+	return NewValidator().
+		Field("description", params.Description, Required(), Length(minAnswerLength, maxAnswerLength)).
+		Field("editSummary", params.EditSummary, MaxLength(maxEditSummaryLength)).
+		Errors()
+}
+
+func (params RollbackAnswerParams) Validate() ValidationErrors {
+	return NewValidator().
+		Field("revision", params.Revision, Required(), Min(1)).
+		Field("editSummary", params.EditSummary, MaxLength(maxEditSummaryLength)).
+		Errors()
+}
+
+func NewQuestionRevisionDiff(from, to *QuestionRevision) *QuestionRevisionDiff {
+	added, removed := utils.DiffStrings(from.TagNames, to.TagNames)
+
+	return &QuestionRevisionDiff{
+		QuestionID: to.QuestionID,
+		From: from.Revision,
+		To: to.Revision,
+		Title: utils.DiffLines(from.Title, to.Title),
+		Description: utils.DiffLines(from.Description, to.Description),
+		TagsAdded: added,
+		TagsRemoved: removed,
+	}
+}
+
+type AnswerRevisionDiff struct {
+	AnswerID primitive.ObjectID `json:"answerID"`
+	From int `json:"from"`
+	To int `json:"to"`
+	Description []utils.DiffLine `json:"description"`
+}
+
+func NewAnswerRevisionDiff(from, to *AnswerRevision) *AnswerRevisionDiff {
+	return &AnswerRevisionDiff{
+		AnswerID: to.AnswerID,
+		From: from.Revision,
+		To: to.Revision,
+		Description: utils.DiffLines(from.Description, to.Description),
+	}
+}
@@ -0,0 +1,117 @@
+package types
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+type Role string
+
+const (
+	RoleUser Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin Role = "admin"
+)
+
+type Permission string
+
+const (
+	PermAccessAdmin Permission = "admin:access"
+	PermModeratePosts Permission = "posts:moderate"
+	PermEditTags Permission = "tags:edit"
+	PermViewPrivateFields Permission = "users:view-private"
+	PermManageRoles Permission = "users:manage-roles"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleUser: {},
+	RoleModerator: {
+		PermAccessAdmin,
+		PermModeratePosts,
+		PermEditTags,
+		PermViewPrivateFields,
+	},
+	RoleAdmin: {
+		PermAccessAdmin,
+		PermModeratePosts,
+		PermEditTags,
+		PermViewPrivateFields,
+		PermManageRoles,
+	},
+}
+
+// Action is something a user does to a post owned by someone, checked with
+// Authorize.
+type Action string
+
+const (
+	ActionEditPost Action = "edit"
+	ActionDeletePost Action = "delete"
+	ActionVotePost Action = "vote"
+)
+
+type UpdateUserRoleParams struct {
+	Role Role `json:"role"`
+}
+
+func (params UpdateUserRoleParams) Validate() ValidationErrors {
+	return NewValidator().
+		Field("role", params.Role, Required(), Valid(params.Role.IsValid(), "is not a role")).
+		Errors()
+}
+
+func (r Role) IsValid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// GetRole returns the user's role. Documents created before roles existed
+// only carry IsAdmin.
+func (u *User) GetRole() Role {
+	if u.IsAdmin {
+		return RoleAdmin
+	}
+	if u.Role == "" {
+		return RoleUser
+	}
+	return u.Role
+}
+
+func (u *User) Can(perm Permission) bool {
+	for _, p := range rolePermissions[u.GetRole()] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// MinEditAnyPostReputation is the reputation from which users may edit
+// posts of others.
+const MinEditAnyPostReputation = 2000
+
+// Authorize is the single ownership policy for posts: owners may edit and
+// delete their own posts but not vote on them, trusted users may edit any
+// post, and anyone who can moderate posts overrides the edit and delete
+// rules. Nobody votes on their own posts, moderators included.
+func Authorize(user *User, action Action, ownerID primitive.ObjectID) bool {
+	if user == nil {
+		return false
+	}
+
+	isOwner := user.ID == ownerID
+
+	if action == ActionVotePost {
+		return !isOwner
+	}
+
+	if user.Can(PermModeratePosts) {
+		return true
+	}
+
+	switch action {
+	case ActionEditPost:
+		return isOwner || user.Reputation >= MinEditAnyPostReputation
+	case ActionDeletePost:
+		return isOwner
+	}
+
+	return false
+}
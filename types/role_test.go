@@ -0,0 +1,41 @@
+package types
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAuthorize(t *testing.T) {
+	owner := primitive.NewObjectID()
+	other := primitive.NewObjectID()
+
+	tests := []struct {
+		name string
+		user *User
+		action Action
+		want bool
+	}{
+		{name: "anonymous", user: nil, action: ActionVotePost, want: false},
+		{name: "owner edits", user: &User{ID: owner}, action: ActionEditPost, want: true},
+		{name: "owner deletes", user: &User{ID: owner}, action: ActionDeletePost, want: true},
+		{name: "owner votes", user: &User{ID: owner}, action: ActionVotePost, want: false},
+		{name: "other edits", user: &User{ID: other}, action: ActionEditPost, want: false},
+		{name: "trusted edits", user: &User{ID: other, Reputation: MinEditAnyPostReputation}, action: ActionEditPost, want: true},
+		{name: "trusted deletes", user: &User{ID: other, Reputation: MinEditAnyPostReputation}, action: ActionDeletePost, want: false},
+		{name: "other votes", user: &User{ID: other}, action: ActionVotePost, want: true},
+		{name: "moderator edits", user: &User{ID: other, Role: RoleModerator}, action: ActionEditPost, want: true},
+		{name: "moderator deletes", user: &User{ID: other, Role: RoleModerator}, action: ActionDeletePost, want: true},
+		{name: "moderator votes on own post", user: &User{ID: owner, Role: RoleModerator}, action: ActionVotePost, want: false},
+		{name: "admin votes on own post", user: &User{ID: owner, IsAdmin: true}, action: ActionVotePost, want: false},
+		{name: "unknown action", user: &User{ID: owner}, action: Action("pin"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Authorize(tt.user, tt.action, owner); got != tt.want {
+				t.Fatalf("Authorize(%s) = %v, want %v", tt.action, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	SearchTypeQuestion = "question"
+	SearchTypeAnswer = "answer"
+	SearchTypeUser = "user"
+	SearchTypeTag = "tag"
+)
+
+// SearchQuery is a parsed search string. Besides free text it understands
+// "[tag]", "user:<id>", "is:answered", "is:unanswered" and "score:>N".
+type SearchQuery struct {
+	Text string
+	Terms []string
+	Tags []string
+	UserID string
+	IsAnswered *bool
+	MinScore *int
+	MaxScore *int
+}
+
+type SearchResult struct {
+	Type string `json:"type"`
+	ID primitive.ObjectID `json:"id"`
+	Title string `json:"title"`
+	Snippet string `json:"snippet"`
+	QuestionID *primitive.ObjectID `json:"questionID,omitempty"`
+	Relevance float64 `json:"relevance"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type SearchResults struct {
+	Query string `json:"query"`
+	Results []*SearchResult `json:"results"`
+	Total int64 `json:"total"`
+	Page int64 `json:"page"`
+	Limit int64 `json:"limit"`
+	HasNext bool `json:"hasNext"`
+}
+
+func ParseSearchQuery(raw string) *SearchQuery {
+	query := &SearchQuery{}
+	text := []string{}
+
+	for _, token := range strings.Fields(raw) {
+		lower := strings.ToLower(token)
+
+		switch {
+		case len(token) > 2 && strings.HasPrefix(token, "[") && strings.HasSuffix(token, "]"):
+			query.Tags = append(query.Tags, token[1:len(token)-1])
+		case strings.HasPrefix(lower, "user:") && len(token) > len("user:"):
+			query.UserID = token[len("user:"):]
+		case lower == "is:answered":
+			answered := true
+			query.IsAnswered = &answered
+		case lower == "is:unanswered":
+			answered := false
+			query.IsAnswered = &answered
+		case strings.HasPrefix(lower, "score:") && query.parseScore(lower[len("score:"):]):
+		default:
+			text = append(text, token)
+		}
+	}
+
+	query.Text = strings.Join(text, " ")
+	for _, term := range text {
+		term = strings.Trim(term, `"-`)
+		if term != "" {
+			query.Terms = append(query.Terms, term)
+		}
+	}
+
+	return query
+}
+
+// HasPostFilters reports whether the query uses operators that only apply
+// to questions and answers.
+func (q *SearchQuery) HasPostFilters() bool {
+	return q.UserID != "" || q.MinScore != nil || q.MaxScore != nil || q.HasQuestionFilters()
+}
+
+// HasQuestionFilters reports whether the query uses operators that only
+// apply to questions.
+func (q *SearchQuery) HasQuestionFilters() bool {
+	return len(q.Tags) > 0 || q.IsAnswered != nil
+}
+
+// parseScore understands ">N", ">=N", "<N", "<=N" and "N".
+func (q *SearchQuery) parseScore(expr string) bool {
+	var (
+		op string
+		value = expr
+	)
+
+	for _, prefix := range []string{">=", "<=", ">", "<"} {
+		if strings.HasPrefix(expr, prefix) {
+			op = prefix
+			value = expr[len(prefix):]
+			break
+		}
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return false
+	}
+
+	switch op {
+	case ">":
+		n++
+		q.MinScore = &n
+	case ">=":
+		q.MinScore = &n
+	case "<":
+		n--
+		q.MaxScore = &n
+	case "<=":
+		q.MaxScore = &n
+	default:
+		min, max := n, n
+		q.MinScore, q.MaxScore = &min, &max
+	}
+
+	return true
+}
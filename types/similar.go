@@ -0,0 +1,76 @@
+package types
+
+import (
+	"time"
+
+	"github.com/fullstack/dev-overflow/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// NearDuplicateSimilarity is the title similarity from which asking
+	// needs the "not a duplicate" acknowledgement.
+	NearDuplicateSimilarity = 0.8
+	minSimilarityScore = 0.1
+)
+
+// QuestionDraft is a question that is still being written.
+type QuestionDraft struct {
+	Title string `json:"title"`
+	Description string `json:"description"`
+	Tags []string `json:"tags"`
+}
+
+type SimilarQuestion struct {
+	ID primitive.ObjectID `json:"id"`
+	Title string `json:"title"`
+	AnswerCount int `json:"answerCount"`
+	IsSolved bool `json:"isSolved"`
+	TitleSimilarity float64 `json:"titleSimilarity"`
+	Score float64 `json:"score"`
+	NearDuplicate bool `json:"nearDuplicate"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (draft QuestionDraft) Validate() ValidationErrors {
+	return NewValidator().
+		Field("title", draft.Title, Required(), Valid(len(utils.Tokenize(draft.Title)) > 0, "must contain words")).
+		Field("tags", draft.Tags, MaxItems(maxTagsLength)).
+		Errors()
+}
+
+// RankSimilarQuestion scores question against the draft. Text similarity
+// over titles and descriptions is weighted up by shared tags and by the
+// question having answers. It returns nil when the two are unrelated.
+func RankSimilarQuestion(draft *QuestionDraft, draftTags []primitive.ObjectID, question *Question) *SimilarQuestion {
+	var (
+		titleSimilarity = utils.Jaccard(utils.Tokenize(draft.Title), utils.Tokenize(question.Title))
+		descriptionSimilarity = utils.Jaccard(utils.Tokenize(draft.Description), utils.Tokenize(question.Description))
+		tagOverlap = utils.Jaccard(draftTags, question.Tags)
+		score = 0.7*titleSimilarity + 0.3*descriptionSimilarity
+	)
+
+	if score < minSimilarityScore {
+		return nil
+	}
+
+	score *= 1 + 0.5*tagOverlap
+
+	switch {
+	case question.AcceptedAnswerID != nil:
+		score *= 1.3
+	case question.AnswerCount > 0:
+		score *= 1.15
+	}
+
+	return &SimilarQuestion{
+		ID: question.ID,
+		Title: question.Title,
+		AnswerCount: question.AnswerCount,
+		IsSolved: question.AcceptedAnswerID != nil,
+		TitleSimilarity: titleSimilarity,
+		Score: score,
+		NearDuplicate: titleSimilarity >= NearDuplicateSimilarity,
+		CreatedAt: question.CreatedAt,
+	}
+}
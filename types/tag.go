@@ -6,13 +6,20 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	maxTagNameLength = 35
+	maxTagDescriptionLength = 500
+)
+
 type Tag struct {
 	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
 	Description string `bson:"description" json:"description"`
 	Name string `bson:"name" json:"name"`
-	Questions []primitive.ObjectID `bson:"questions" json:"questions"`
+	QuestionCount int `bson:"questionCount" json:"questionCount"`
 	QuestionDetails []*Question `bson:"questionDetails,omitempty" json:"questionDetails,omitempty"`
-	Followers []primitive.ObjectID `bson:"followers" json:"followers"`
+	// Followers only backs FollowerCount and is never sent to clients.
+	Followers []primitive.ObjectID `bson:"followers" json:"-"`
+	FollowerCount int `bson:"followerCount" json:"followerCount"`
 	FollowersDetails []*User `bson:"followersDetails,omitempty" json:"followersDetails,omitempty"`
 	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
 }
@@ -21,7 +28,24 @@ type CreateTagParams struct {
 	Name string `json:"name"`
 }
 
-type UpdateTagQuestionAndFollowers struct {
-	Questions primitive.ObjectID `json:"questions"`
+type EditTagParams struct {
+	Name string `json:"name"`
+	Description string `json:"description"`
+}
+
+type UpdateTagFollowers struct {
 	Followers primitive.ObjectID `json:"followers"`
-}
\ No newline at end of file
+}
+
+func (params CreateTagParams) Validate() ValidationErrors {
+	return NewValidator().
+		Field("name", params.Name, Required(), MaxLength(maxTagNameLength)).
+		Errors()
+}
+
+func (params EditTagParams) Validate() ValidationErrors {
+	return NewValidator().
+		Field("name", params.Name, Required(), MaxLength(maxTagNameLength)).
+		Field("description", params.Description, MaxLength(maxTagDescriptionLength)).
+		Errors()
+}
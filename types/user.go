@@ -1,10 +1,11 @@
 package types
 
 import (
-	"fmt"
 	"time"
 
 	"net/mail"
+	"net/url"
+	"strings"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"golang.org/x/crypto/bcrypt"
@@ -15,6 +16,10 @@ const (
 	minFirstNameLen = 2
 	minLastNameLen = 2
 	minPasswordLen = 7
+	maxNameLen = 50
+	maxBioLen = 500
+	maxLocationLen = 100
+	maxURLLen = 2048
 )
 
 type User struct {
@@ -28,14 +33,41 @@ type User struct {
 	EncryptedPassword string `bson:"password" json:"-"`
 	Location string `bson:"location" json:"location"`
 	PortfolioWebsite string `bson:"portfolioWebsite" json:"portfolioWebsite"`
-	Questions []primitive.ObjectID `bson:"questions" json:"questions"`
-	Answers []primitive.ObjectID `bson:"answers" json:"answers"`
+	QuestionCount int `bson:"questionCount" json:"questionCount"`
+	AnswerCount int `bson:"answerCount" json:"answerCount"`
 	IsAdmin bool `bson:"isAdmin" json:"isAdmin"`
+	Role Role `bson:"role,omitempty" json:"role,omitempty"`
 	Reputation int `bson:"reputation" json:"reputation"`
 	Saved []primitive.ObjectID `bson:"saved" json:"saved"`
 	JoinedAt time.Time `bson:"joinedAt" json:"joinedAt"`
 }
 
+// PublicUser is the profile anyone may see. The question and answer
+// pipelines project the embedded author straight into it.
+type PublicUser struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Name string `bson:"name" json:"name"`
+	Picture string `bson:"picture" json:"picture"`
+	Bio string `bson:"bio" json:"bio"`
+	Location string `bson:"location" json:"location"`
+	PortfolioWebsite string `bson:"portfolioWebsite" json:"portfolioWebsite"`
+	Reputation int `bson:"reputation" json:"reputation"`
+	QuestionCount int `bson:"questionCount" json:"questionCount"`
+	AnswerCount int `bson:"answerCount" json:"answerCount"`
+	JoinedAt time.Time `bson:"joinedAt" json:"joinedAt"`
+}
+
+// PrivateUser is the "me" view returned to the account owner.
+type PrivateUser struct {
+	PublicUser
+	ClerkID string `json:"clerkID"`
+	FirstName string `json:"firstName"`
+	LastName string `json:"lastName"`
+	Email string `json:"email"`
+	Role Role `json:"role"`
+	Saved []primitive.ObjectID `json:"saved"`
+}
+
 type CreateUserParam struct {
 	FirstName string `json:"firstName"`
 	LastName string `json:"lastName"`
@@ -45,13 +77,40 @@ type CreateUserParam struct {
 	Picture string `json:"picture"`
 }
 
+// UserUpdate is implemented by the typed params accepted by
+// UserStore.UpdateUser, so only allow-listed fields ever reach $set.
+type UserUpdate interface {
+	Fields() map[string]interface{}
+}
+
+// UpdateUserParam holds the profile fields a user may change on their own
+// account. Nil fields are left untouched.
 type UpdateUserParam struct {
-	UpdateData map[string]interface{} `json:"updateData"`
+	FirstName *string `json:"firstName"`
+	LastName *string `json:"lastName"`
+	Bio *string `json:"bio"`
+	Picture *string `json:"picture"`
+	Location *string `json:"location"`
+	PortfolioWebsite *string `json:"portfolioWebsite"`
+}
+
+// AdminUpdateUserParam adds the privileged fields that only admins and the
+// Clerk sync may change. Reputation is not one of them, it only moves
+// through the reputation ledger.
+type AdminUpdateUserParam struct {
+	UpdateUserParam
+	Email *string `json:"email"`
 }
 
 type SaveQuestionParam struct {
 	QuestionID string `json:"questionID"`
-	UserID string `json:"userID"`
+	UserID string `json:"-"`
+}
+
+func (params SaveQuestionParam) Validate() ValidationErrors {
+	return NewValidator().
+		Field("questionID", params.QuestionID, Required()).
+		Errors()
 }
 
 func NewUserFromParams(params CreateUserParam) (*User, error) {
@@ -67,13 +126,42 @@ func NewUserFromParams(params CreateUserParam) (*User, error) {
 		Email: params.Email,
 		Picture: params.Picture,
 		// EncryptedPassword: string(encpw),
-		Questions: []primitive.ObjectID{},
-		Answers: []primitive.ObjectID{},
 		JoinedAt: time.Now().UTC(),
 		Saved: []primitive.ObjectID{},
 	}, nil
 }
 
+func (u *User) DisplayName() string {
+	return strings.TrimSpace(u.FirstName + " " + u.LastName)
+}
+
+func (u *User) Public() *PublicUser {
+	return &PublicUser{
+		ID: u.ID,
+		Name: u.DisplayName(),
+		Picture: u.Picture,
+		Bio: u.Bio,
+		Location: u.Location,
+		PortfolioWebsite: u.PortfolioWebsite,
+		Reputation: u.Reputation,
+		QuestionCount: u.QuestionCount,
+		AnswerCount: u.AnswerCount,
+		JoinedAt: u.JoinedAt,
+	}
+}
+
+func (u *User) Private() *PrivateUser {
+	return &PrivateUser{
+		PublicUser: *u.Public(),
+		ClerkID: u.ClerkID,
+		FirstName: u.FirstName,
+		LastName: u.LastName,
+		Email: u.Email,
+		Role: u.GetRole(),
+		Saved: u.Saved,
+	}
+}
+
 func isValid(email string) bool {
 	_, err := mail.ParseAddress(email)
 	return err == nil
@@ -83,48 +171,67 @@ func IsValidPassword(encpw, pw string) bool {
 	return bcrypt.CompareHashAndPassword([]byte(encpw), []byte(pw)) == nil
 }
 
-func (params UpdateUserParam) Validate() map[string]string {
-	errors := map[string]string{}
-
-	if firstName, ok := params.UpdateData["firstName"].(string); ok {
-		if len(firstName) < minFirstNameLen {
-			errors["FirstName"] = fmt.Sprintf("First Name must be at least %d characters", minFirstNameLen)
-		}
+func isValidURL(rawURL string) bool {
+	u, err := url.ParseRequestURI(rawURL)
+	if err != nil {
+		return false
 	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
 
-	if lastName, ok := params.UpdateData["lastName"].(string); ok {
-		if len(lastName) < minFirstNameLen {
-			errors["FirstName"] = fmt.Sprintf("Last Name must be at least %d characters", minFirstNameLen)
-		}
-	}
+func (params UpdateUserParam) Validate() ValidationErrors {
+	return NewValidator().
+		Field("firstName", params.FirstName, Length(minFirstNameLen, maxNameLen)).
+		Field("lastName", params.LastName, Length(minLastNameLen, maxNameLen)).
+		Field("bio", params.Bio, MaxLength(maxBioLen)).
+		Field("location", params.Location, MaxLength(maxLocationLen)).
+		Field("picture", params.Picture, URL()).
+		Field("portfolioWebsite", params.PortfolioWebsite, URL()).
+		Errors()
+}
+
+// Fields returns the bson field names and values of the non nil params.
+func (params UpdateUserParam) Fields() map[string]interface{} {
+	fields := map[string]interface{}{}
 
-	if email, ok := params.UpdateData["email"].(string); ok {
-		if !isValid(email) {
-			errors["Email"] = fmt.Sprintf("Your email %s is not a valid email", email)
+	setString := func(key string, value *string) {
+		if value != nil {
+			fields[key] = *value
 		}
 	}
 
-	return errors
+	setString("firstName", params.FirstName)
+	setString("lastName", params.LastName)
+	setString("bio", params.Bio)
+	setString("picture", params.Picture)
+	setString("location", params.Location)
+	setString("portfolioWebsite", params.PortfolioWebsite)
+
+	return fields
 }
 
-func (params CreateUserParam) Validate() map[string]string {
-	errors := map[string]string{}
+func (params AdminUpdateUserParam) Validate() ValidationErrors {
+	return NewValidator().
+		Merge(params.UpdateUserParam.Validate()).
+		Field("email", params.Email, Email()).
+		Errors()
+}
 
-	if len(params.FirstName) < minFirstNameLen {
-		errors["FirstName"] = fmt.Sprintf("First Name must be at least %d characters", minFirstNameLen)
-	}
-	
-	if len(params.LastName) < minLastNameLen {
-		errors["LastName"] = fmt.Sprintf("Last Name must be at least %d characters", minLastNameLen)
-	}
+func (params AdminUpdateUserParam) Fields() map[string]interface{} {
+	fields := params.UpdateUserParam.Fields()
 
-	if !isValid(params.Email) {
-		errors["Email"] = fmt.Sprintf("Your email %s is not a valid email", params.Email)
+	if params.Email != nil {
+		fields["email"] = *params.Email
 	}
 
-	// if len(params.Password) < minPasswordLen {
-	// 	errors["Password"] = fmt.Sprintf("Password must be at least %d characters", minPasswordLen)
-	// }
+	return fields
+}
 
-	return errors
-}
\ No newline at end of file
+func (params CreateUserParam) Validate() ValidationErrors {
+	return NewValidator().
+		Field("firstName", params.FirstName, Required(), Length(minFirstNameLen, maxNameLen)).
+		Field("lastName", params.LastName, Required(), Length(minLastNameLen, maxNameLen)).
+		Field("email", params.Email, Required(), Email()).
+		Field("picture", params.Picture, URL()).
+		Errors()
+}
@@ -0,0 +1,236 @@
+package types
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ValidationErrors maps the json name of each invalid field to what is wrong
+// with it.
+type ValidationErrors map[string]string
+
+// Rule is one declarative check on a field value. Pointers are
+// dereferenced first and a nil pointer, an omitted optional field, is only
+// checked by Required. A pointer to a zero number or bool counts as given.
+type Rule struct {
+	required bool
+	check func(v reflect.Value) string
+}
+
+// Validator collects the errors of a params struct field by field. Only the
+// first failing rule of a field is reported.
+type Validator struct {
+	errors ValidationErrors
+}
+
+func NewValidator() *Validator {
+	return &Validator{errors: ValidationErrors{}}
+}
+
+func (v *Validator) Field(name string, value interface{}, rules ...Rule) *Validator {
+	if _, failed := v.errors[name]; failed {
+		return v
+	}
+
+	rv := reflect.ValueOf(value)
+	present, given := rv.IsValid(), false
+	for present && rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			present = false
+			break
+		}
+		rv, given = rv.Elem(), true
+	}
+
+	switch rv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		given = false
+	}
+
+	for _, rule := range rules {
+		if (!present && !rule.required) || (given && rule.required) {
+			continue
+		}
+		if problem := rule.check(rv); problem != "" {
+			v.errors[name] = fieldLabel(name) + " " + problem
+			break
+		}
+	}
+
+	return v
+}
+
+// Add records an error no rule expresses, such as one spanning fields.
+func (v *Validator) Add(name, message string) *Validator {
+	if _, failed := v.errors[name]; !failed {
+		v.errors[name] = message
+	}
+	return v
+}
+
+// Merge adds the errors of an embedded params struct.
+func (v *Validator) Merge(errors ValidationErrors) *Validator {
+	for name, message := range errors {
+		v.Add(name, message)
+	}
+	return v
+}
+
+func (v *Validator) Errors() ValidationErrors {
+	return v.errors
+}
+
+func Required() Rule {
+	return Rule{required: true, check: func(v reflect.Value) string {
+		if !v.IsValid() {
+			return "is required"
+		}
+		switch v.Kind() {
+		case reflect.String:
+			if strings.TrimSpace(v.String()) == "" {
+				return "is required"
+			}
+		case reflect.Slice, reflect.Map:
+			if v.Len() == 0 {
+				return "is required"
+			}
+		default:
+			if v.IsZero() {
+				return "is required"
+			}
+		}
+		return ""
+	}}
+}
+
+func MinLength(min int) Rule {
+	return Length(min, -1)
+}
+
+func MaxLength(max int) Rule {
+	return Length(0, max)
+}
+
+// Length checks the number of characters of a string. A negative max means
+// no upper bound.
+func Length(min, max int) Rule {
+	return Rule{check: func(v reflect.Value) string {
+		if v.Kind() != reflect.String {
+			return ""
+		}
+		n := utf8.RuneCountInString(strings.TrimSpace(v.String()))
+		switch {
+		case min > 0 && max >= 0 && (n < min || n > max):
+			return fmt.Sprintf("must be between %d and %d characters", min, max)
+		case n < min:
+			return fmt.Sprintf("must be at least %d characters", min)
+		case max >= 0 && n > max:
+			return fmt.Sprintf("must be at most %d characters", max)
+		}
+		return ""
+	}}
+}
+
+func MinItems(min int) Rule {
+	return Rule{check: func(v reflect.Value) string {
+		if v.Kind() == reflect.Slice && v.Len() < min {
+			return fmt.Sprintf("must have at least %d items", min)
+		}
+		return ""
+	}}
+}
+
+func MaxItems(max int) Rule {
+	return Rule{check: func(v reflect.Value) string {
+		if v.Kind() == reflect.Slice && v.Len() > max {
+			return fmt.Sprintf("must have at most %d items", max)
+		}
+		return ""
+	}}
+}
+
+// Between checks an integer is within min and max inclusive.
+func Between(min, max int) Rule {
+	return Rule{check: func(v reflect.Value) string {
+		if !v.CanInt() {
+			return ""
+		}
+		if n := v.Int(); n < int64(min) || n > int64(max) {
+			return fmt.Sprintf("must be between %d and %d", min, max)
+		}
+		return ""
+	}}
+}
+
+func Min(min int) Rule {
+	return Rule{check: func(v reflect.Value) string {
+		if v.CanInt() && v.Int() < int64(min) {
+			return fmt.Sprintf("must be at least %d", min)
+		}
+		return ""
+	}}
+}
+
+// URL accepts absolute http and https URLs. Empty strings pass, combine
+// with Required when the field is mandatory.
+func URL() Rule {
+	return Rule{check: func(v reflect.Value) string {
+		if v.Kind() != reflect.String || v.String() == "" {
+			return ""
+		}
+		if len(v.String()) > maxURLLen || !isValidURL(v.String()) {
+			return "must be a valid http or https URL"
+		}
+		return ""
+	}}
+}
+
+// Email accepts RFC 5322 addresses. Empty strings pass, combine with
+// Required when the field is mandatory.
+func Email() Rule {
+	return Rule{check: func(v reflect.Value) string {
+		if v.Kind() != reflect.String || v.String() == "" {
+			return ""
+		}
+		if !isValid(v.String()) {
+			return "must be a valid email address"
+		}
+		return ""
+	}}
+}
+
+// Valid fails with problem unless ok, for checks specific to one type like
+// enum membership.
+func Valid(ok bool, problem string) Rule {
+	return Rule{check: func(reflect.Value) string {
+		if !ok {
+			return problem
+		}
+		return ""
+	}}
+}
+
+// fieldLabel turns a json field name into the subject of a message,
+// "portfolioWebsite" becomes "Portfolio website".
+func fieldLabel(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && unicode.IsLower(runes[i-1]) {
+			b.WriteRune(' ')
+		}
+		if i > 0 && unicode.IsUpper(r) && (i+1 == len(runes) || unicode.IsUpper(runes[i+1])) {
+			b.WriteRune(r)
+			continue
+		}
+		if i == 0 {
+			b.WriteRune(unicode.ToUpper(r))
+		} else {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
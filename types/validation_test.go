@@ -0,0 +1,73 @@
+package types
+
+import "testing"
+
+func TestValidatorRules(t *testing.T) {
+	var (
+		zero = 0
+		six = 6
+		nilInt *int
+	)
+
+	tests := []struct {
+		name string
+		field string
+		value interface{}
+		rules []Rule
+		want string
+	}{
+		{name: "required missing", field: "title", value: "", rules: []Rule{Required()}, want: "Title is required"},
+		{name: "required blank", field: "title", value: "   ", rules: []Rule{Required()}, want: "Title is required"},
+		{name: "required empty slice", field: "tags", value: []string{}, rules: []Rule{Required()}, want: "Tags is required"},
+		{name: "required nil pointer", field: "vote", value: nilInt, rules: []Rule{Required()}, want: "Vote is required"},
+		{name: "required pointer to zero", field: "vote", value: &zero, rules: []Rule{Required()}},
+		{name: "first failing rule wins", field: "title", value: "", rules: []Rule{Required(), MinLength(5)}, want: "Title is required"},
+		{name: "length in range", field: "title", value: "hello", rules: []Rule{Length(3, 10)}},
+		{name: "length too short", field: "title", value: "ab", rules: []Rule{Length(3, 10)}, want: "Title must be between 3 and 10 characters"},
+		{name: "length ignores surrounding spaces", field: "title", value: "  ab  ", rules: []Rule{MinLength(3)}, want: "Title must be at least 3 characters"},
+		{name: "max length", field: "bio", value: "abcd", rules: []Rule{MaxLength(3)}, want: "Bio must be at most 3 characters"},
+		{name: "max length counts runes", field: "bio", value: "héé", rules: []Rule{MaxLength(3)}},
+		{name: "min items", field: "tags", value: []string{"go"}, rules: []Rule{MinItems(2)}, want: "Tags must have at least 2 items"},
+		{name: "max items", field: "tags", value: []string{"a", "b", "c"}, rules: []Rule{MaxItems(2)}, want: "Tags must have at most 2 items"},
+		{name: "between", field: "amount", value: 6, rules: []Rule{Between(1, 5)}, want: "Amount must be between 1 and 5"},
+		{name: "between pointer", field: "amount", value: &six, rules: []Rule{Between(1, 5)}, want: "Amount must be between 1 and 5"},
+		{name: "optional nil pointer skipped", field: "amount", value: nilInt, rules: []Rule{Min(1)}},
+		{name: "min", field: "revision", value: 0, rules: []Rule{Min(1)}, want: "Revision must be at least 1"},
+		{name: "url", field: "portfolioWebsite", value: "https://example.com", rules: []Rule{URL()}},
+		{name: "url empty", field: "portfolioWebsite", value: "", rules: []Rule{URL()}},
+		{name: "url scheme", field: "portfolioWebsite", value: "ftp://example.com", rules: []Rule{URL()}, want: "Portfolio website must be a valid http or https URL"},
+		{name: "email", field: "email", value: "jane@example.com", rules: []Rule{Email()}},
+		{name: "email invalid", field: "email", value: "jane", rules: []Rule{Email()}, want: "Email must be a valid email address"},
+		{name: "valid", field: "reason", value: "spam", rules: []Rule{Valid(true, "is not a reason")}},
+		{name: "invalid", field: "reason", value: "other", rules: []Rule{Valid(false, "is not a reason")}, want: "Reason is not a reason"},
+		{name: "label of acronym", field: "answerID", value: "", rules: []Rule{Required()}, want: "Answer ID is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errors := NewValidator().Field(tt.field, tt.value, tt.rules...).Errors()
+
+			got, failed := errors[tt.field]
+			if tt.want == "" && failed {
+				t.Fatalf("unexpected error %q", got)
+			}
+			if got != tt.want {
+				t.Fatalf("error = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatorMerge(t *testing.T) {
+	errors := NewValidator().
+		Field("title", "", Required()).
+		Merge(ValidationErrors{"title": "merged", "tags": "Tags is required"}).
+		Errors()
+
+	if errors["title"] != "Title is required" {
+		t.Fatalf("merge overwrote title: %q", errors["title"])
+	}
+	if errors["tags"] != "Tags is required" {
+		t.Fatalf("merge dropped tags: %q", errors["tags"])
+	}
+}
@@ -0,0 +1,78 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// VoteState is a user's vote on a post.
+type VoteState int
+
+const (
+	VoteDown VoteState = -1
+	VoteNone VoteState = 0
+	VoteUp VoteState = 1
+)
+
+// VoteTarget is the kind of post a vote is cast on.
+type VoteTarget string
+
+const (
+	VoteOnQuestion VoteTarget = "question"
+	VoteOnAnswer VoteTarget = "answer"
+)
+
+// Vote is one user's standing vote on a post, at most one per user and
+// target. QuestionID is the question itself or the one an answer belongs to
+// and OwnerID the author of the target, so cascades and audits need no
+// lookup. Reviewed votes were restored by a moderator and are exempt from
+// fraud detection.
+type Vote struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID primitive.ObjectID `bson:"userID" json:"userID"`
+	TargetID primitive.ObjectID `bson:"targetID" json:"targetID"`
+	TargetType VoteTarget `bson:"targetType" json:"targetType"`
+	QuestionID primitive.ObjectID `bson:"questionID" json:"questionID"`
+	OwnerID primitive.ObjectID `bson:"ownerID" json:"ownerID"`
+	Vote VoteState `bson:"vote" json:"vote"`
+	Reviewed bool `bson:"reviewed,omitempty" json:"reviewed,omitempty"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
+}
+
+// VoteResult is the caller's vote on a post after voting and the post's new
+// score.
+type VoteResult struct {
+	PostID primitive.ObjectID `json:"postID"`
+	Vote VoteState `json:"vote"`
+	Score int `json:"score"`
+	Upvotes int `json:"upvotes"`
+	Downvotes int `json:"downvotes"`
+}
+
+// VoteParams sets the caller's vote on a post: 1 votes up, -1 votes down
+// and 0 clears the vote.
+type VoteParams struct {
+	Vote *VoteState `json:"vote"`
+}
+
+// Post returns the post the vote is on, for recording its reputation.
+func (v *Vote) Post() PostRef {
+	post := PostRef{QuestionID: v.QuestionID, OwnerID: v.OwnerID}
+	if v.TargetType == VoteOnAnswer {
+		answerID := v.TargetID
+		post.AnswerID = &answerID
+	}
+	return post
+}
+
+func (s VoteState) IsValid() bool {
+	return s >= VoteDown && s <= VoteUp
+}
+
+func (params VoteParams) Validate() ValidationErrors {
+	return NewValidator().
+		Field("vote", params.Vote, Required(), Valid(params.Vote != nil && params.Vote.IsValid(), "must be 1 to vote up, -1 to vote down or 0 to clear")).
+		Errors()
+}
@@ -0,0 +1,147 @@
+package utils
+
+import "strings"
+
+const (
+	DiffEqual = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+type DiffLine struct {
+	Op string `json:"op"`
+	Text string `json:"text"`
+}
+
+// maxDiffCells bounds the LCS table DiffLines allocates. Bodies whose
+// changed lines would need more are diffed as one replaced block.
+const maxDiffCells = 1000000
+
+// DiffLines returns a line based diff turning a into b. Lines shared at the
+// start and end are kept as is and the changed middle is computed from the
+// longest common subsequence of its lines.
+func DiffLines(a, b string) []DiffLine {
+	var (
+		oldLines = splitLines(a)
+		newLines = splitLines(b)
+		prefix = 0
+		suffix = 0
+	)
+
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+
+	diff := []DiffLine{}
+	for _, line := range oldLines[:prefix] {
+		diff = append(diff, DiffLine{Op: DiffEqual, Text: line})
+	}
+
+	diff = append(diff, diffMiddle(oldLines[prefix:len(oldLines)-suffix], newLines[prefix:len(newLines)-suffix])...)
+
+	for _, line := range oldLines[len(oldLines)-suffix:] {
+		diff = append(diff, DiffLine{Op: DiffEqual, Text: line})
+	}
+
+	return diff
+}
+
+func diffMiddle(oldLines, newLines []string) []DiffLine {
+	var (
+		n = len(oldLines)
+		m = len(newLines)
+		diff = []DiffLine{}
+	)
+
+	if (n+1)*(m+1) > maxDiffCells {
+		for _, line := range oldLines {
+			diff = append(diff, DiffLine{Op: DiffDelete, Text: line})
+		}
+		for _, line := range newLines {
+			diff = append(diff, DiffLine{Op: DiffInsert, Text: line})
+		}
+		return diff
+	}
+
+	// lcs[i][j] is the LCS length of oldLines[i:] and newLines[j:].
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case oldLines[i] == newLines[j]:
+			diff = append(diff, DiffLine{Op: DiffEqual, Text: oldLines[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, DiffLine{Op: DiffDelete, Text: oldLines[i]})
+			i++
+		default:
+			diff = append(diff, DiffLine{Op: DiffInsert, Text: newLines[j]})
+			j++
+		}
+	}
+
+	for ; i < n; i++ {
+		diff = append(diff, DiffLine{Op: DiffDelete, Text: oldLines[i]})
+	}
+
+	for ; j < m; j++ {
+		diff = append(diff, DiffLine{Op: DiffInsert, Text: newLines[j]})
+	}
+
+	return diff
+}
+
+// DiffStrings returns the items only in b and the items only in a.
+func DiffStrings(a, b []string) (added []string, removed []string) {
+	added, removed = []string{}, []string{}
+
+	inA := make(map[string]bool, len(a))
+	for _, s := range a {
+		inA[s] = true
+	}
+
+	inB := make(map[string]bool, len(b))
+	for _, s := range b {
+		inB[s] = true
+		if !inA[s] {
+			added = append(added, s)
+		}
+	}
+
+	for _, s := range a {
+		if !inB[s] {
+			removed = append(removed, s)
+		}
+	}
+
+	return added, removed
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+}
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a string
+		b string
+		want []DiffLine
+	}{
+		{name: "both empty", a: "", b: "", want: []DiffLine{}},
+		{
+			name: "insert into empty",
+			a: "",
+			b: "one\ntwo",
+			want: []DiffLine{{DiffInsert, "one"}, {DiffInsert, "two"}},
+		},
+		{
+			name: "delete all",
+			a: "one\ntwo",
+			b: "",
+			want: []DiffLine{{DiffDelete, "one"}, {DiffDelete, "two"}},
+		},
+		{
+			name: "unchanged",
+			a: "one\ntwo",
+			b: "one\ntwo",
+			want: []DiffLine{{DiffEqual, "one"}, {DiffEqual, "two"}},
+		},
+		{
+			name: "changed middle line",
+			a: "one\ntwo\nthree",
+			b: "one\n2\nthree",
+			want: []DiffLine{{DiffEqual, "one"}, {DiffDelete, "two"}, {DiffInsert, "2"}, {DiffEqual, "three"}},
+		},
+		{
+			name: "moved line",
+			a: "a\nb\nc\nd",
+			b: "b\nc\na\nd",
+			want: []DiffLine{{DiffDelete, "a"}, {DiffEqual, "b"}, {DiffEqual, "c"}, {DiffInsert, "a"}, {DiffEqual, "d"}},
+		},
+		{
+			name: "windows line endings",
+			a: "one\r\ntwo",
+			b: "one\ntwo\nthree",
+			want: []DiffLine{{DiffEqual, "one"}, {DiffEqual, "two"}, {DiffInsert, "three"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DiffLines(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("DiffLines() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffLinesLargeInput(t *testing.T) {
+	a := "head\n" + strings.Repeat("a\n", 5000) + "tail"
+	b := "head\n" + strings.Repeat("b\n", 5000) + "tail"
+
+	diff := DiffLines(a, b)
+	if len(diff) != 10002 {
+		t.Fatalf("expected 10002 lines, got %d", len(diff))
+	}
+
+	if diff[0] != (DiffLine{DiffEqual, "head"}) || diff[len(diff)-1] != (DiffLine{DiffEqual, "tail"}) {
+		t.Fatalf("expected the shared head and tail to be kept, got %v and %v", diff[0], diff[len(diff)-1])
+	}
+
+	if diff[1].Op != DiffDelete || diff[5001].Op != DiffInsert {
+		t.Fatalf("expected the changed lines as one replaced block")
+	}
+}
+
+func TestDiffStrings(t *testing.T) {
+	added, removed := DiffStrings([]string{"go", "mongo"}, []string{"go", "fiber"})
+
+	if !reflect.DeepEqual(added, []string{"fiber"}) {
+		t.Fatalf("added = %v, want [fiber]", added)
+	}
+
+	if !reflect.DeepEqual(removed, []string{"mongo"}) {
+		t.Fatalf("removed = %v, want [mongo]", removed)
+	}
+}
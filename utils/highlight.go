@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// Highlight cuts a snippet of about width runes around the first matching
+// term and wraps every term match in <mark>. The rest of the text is HTML
+// escaped.
+func Highlight(text string, terms []string, width int) string {
+	start := 0
+	for i := 0; i < len(text); i++ {
+		if matchTerm(text[i:], terms) > 0 {
+			start = i
+			break
+		}
+	}
+
+	// Open the window a little before the first match, snapped to a rune
+	// boundary.
+	start -= width / 4
+	if start < 0 {
+		start = 0
+	}
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+
+	end := start
+	for n := 0; end < len(text) && n < width; n++ {
+		_, size := utf8.DecodeRuneInString(text[end:])
+		end += size
+	}
+
+	snippet := text[start:end]
+	var b strings.Builder
+
+	if start > 0 {
+		b.WriteString("…")
+	}
+
+	for i := 0; i < len(snippet); {
+		if matched := matchTerm(snippet[i:], terms); matched > 0 {
+			b.WriteString("<mark>")
+			b.WriteString(html.EscapeString(snippet[i : i+matched]))
+			b.WriteString("</mark>")
+			i += matched
+			continue
+		}
+
+		_, size := utf8.DecodeRuneInString(snippet[i:])
+		b.WriteString(html.EscapeString(snippet[i : i+size]))
+		i += size
+	}
+
+	if end < len(text) {
+		b.WriteString("…")
+	}
+
+	return b.String()
+}
+
+// matchTerm returns the byte length of the longest term s starts with,
+// ignoring case.
+func matchTerm(s string, terms []string) int {
+	matched := 0
+	for _, term := range terms {
+		if term != "" && len(term) <= len(s) && len(term) > matched && strings.EqualFold(s[:len(term)], term) {
+			matched = len(term)
+		}
+	}
+	return matched
+}
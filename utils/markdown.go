@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	gmhtml "github.com/yuin/goldmark/renderer/html"
+	"golang.org/x/net/html"
+)
+
+// Raw HTML is let through by the Markdown renderer and then cleaned up by
+// the sanitizer, which only keeps the allowlisted elements and attributes.
+var (
+	markdown = goldmark.New(
+		goldmark.WithExtensions(
+			extension.NewTable(extension.WithTableCellAlignMethod(extension.TableCellAlignAttribute)),
+			extension.Strikethrough,
+			extension.Linkify,
+			extension.TaskList,
+		),
+		goldmark.WithRendererOptions(gmhtml.WithUnsafe()),
+	)
+	sanitizer = newPostPolicy()
+)
+
+func newPostPolicy() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+	policy.RequireNoFollowOnLinks(false)
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#.-]+$`)).OnElements("code")
+	policy.AllowAttrs("align").Matching(regexp.MustCompile(`^(left|center|right)$`)).OnElements("th", "td")
+	policy.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	policy.AllowAttrs("checked", "disabled").OnElements("input")
+	return policy
+}
+
+// RenderMarkdown renders GitHub flavored Markdown to sanitized HTML. When
+// nofollow is set links get rel="nofollow ugc".
+func RenderMarkdown(source string, nofollow bool) string {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(source), &buf); err != nil {
+		return html.EscapeString(source)
+	}
+
+	rendered := sanitizer.SanitizeBytes(buf.Bytes())
+	if nofollow {
+		return addLinkRel(rendered, "nofollow ugc")
+	}
+
+	return string(rendered)
+}
+
+// addLinkRel sets rel on every link of already sanitized HTML.
+func addLinkRel(body []byte, rel string) string {
+	var (
+		out strings.Builder
+		tokenizer = html.NewTokenizer(bytes.NewReader(body))
+	)
+
+	for {
+		if tokenizer.Next() == html.ErrorToken {
+			if tokenizer.Err() == io.EOF {
+				return out.String()
+			}
+			return string(body)
+		}
+
+		token := tokenizer.Token()
+		if token.Type == html.StartTagToken && token.Data == "a" {
+			attrs := []html.Attribute{}
+			for _, attr := range token.Attr {
+				if attr.Key != "rel" {
+					attrs = append(attrs, attr)
+				}
+			}
+			token.Attr = append(attrs, html.Attribute{Key: "rel", Val: rel})
+		}
+
+		out.WriteString(token.String())
+	}
+}
@@ -0,0 +1,69 @@
+package utils
+
+import "testing"
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		source string
+		nofollow bool
+		want string
+	}{
+		{name: "script is stripped", source: "<script>alert(1)</script>hi", want: "hi"},
+		{name: "javascript link is stripped", source: "[x](javascript:alert(1))", want: "<p>x</p>\n"},
+		{name: "event handler is stripped", source: `<img src="a.png" onerror="alert(1)">`, want: `<img src="a.png">`},
+		{name: "raw style is stripped", source: `<p style="color:red">hi</p>`, want: "<p>hi</p>"},
+		{
+			name: "fenced code keeps its language",
+			source: "```go\nfmt.Println()\n```",
+			want: "<pre><code class=\"language-go\">fmt.Println()\n</code></pre>\n",
+		},
+		{
+			name: "only language classes survive",
+			source: `<code class="evil">x</code>`,
+			want: "<p><code>x</code></p>\n",
+		},
+		{
+			name: "task list",
+			source: "- [x] done\n- [ ] todo",
+			want: "<ul>\n<li><input checked=\"\" disabled=\"\" type=\"checkbox\"> done</li>\n<li><input disabled=\"\" type=\"checkbox\"> todo</li>\n</ul>\n",
+		},
+		{name: "other inputs are stripped", source: `<input type="text" value="x">`, want: ""},
+		{
+			name: "link without nofollow",
+			source: "[a](https://example.com)",
+			want: "<p><a href=\"https://example.com\">a</a></p>\n",
+		},
+		{
+			name: "link with nofollow",
+			source: "[a](https://example.com)",
+			nofollow: true,
+			want: "<p><a href=\"https://example.com\" rel=\"nofollow ugc\">a</a></p>\n",
+		},
+		{
+			name: "raw rel is dropped without nofollow",
+			source: `<a href="https://example.com" rel="me">a</a>`,
+			want: "<p><a href=\"https://example.com\">a</a></p>\n",
+		},
+		{
+			name: "raw rel is replaced with nofollow",
+			source: `<a href="https://example.com" rel="me">a</a>`,
+			nofollow: true,
+			want: "<p><a href=\"https://example.com\" rel=\"nofollow ugc\">a</a></p>\n",
+		},
+		{
+			name: "autolinks get nofollow",
+			source: "https://example.com",
+			nofollow: true,
+			want: "<p><a href=\"https://example.com\" rel=\"nofollow ugc\">https://example.com</a></p>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderMarkdown(tt.source, tt.nofollow); got != tt.want {
+				t.Errorf("RenderMarkdown(%q, %v) = %q, want %q", tt.source, tt.nofollow, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "can": true, "do": true, "does": true, "for": true,
+	"from": true, "how": true, "i": true, "in": true, "is": true, "it": true,
+	"my": true, "of": true, "on": true, "or": true, "the": true, "this": true,
+	"to": true, "what": true, "when": true, "why": true, "with": true,
+}
+
+// Tokenize returns the distinct lowercase words of s, without stop words.
+func Tokenize(s string) []string {
+	seen := map[string]bool{}
+	tokens := []string{}
+
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '#' && r != '+'
+	})
+
+	for _, word := range words {
+		if stopWords[word] || seen[word] {
+			continue
+		}
+		seen[word] = true
+		tokens = append(tokens, word)
+	}
+
+	return tokens
+}
+
+// Jaccard returns the size of the intersection of a and b over the size of
+// their union, 0 when both are empty.
+func Jaccard[T comparable](a, b []T) float64 {
+	set := make(map[T]bool, len(a))
+	for _, item := range a {
+		set[item] = true
+	}
+
+	union := len(set)
+	shared := 0
+	for _, item := range b {
+		if set[item] {
+			shared++
+			delete(set, item)
+		} else {
+			union++
+		}
+	}
+
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}